@@ -1,28 +1,124 @@
 package groot
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"go/build"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
 
-	"github.com/joho/godotenv"
 	"github.com/ovila98/ers"
 )
 
 // Root env key.
 var grootEnv = "GROOT"
 
+// Root baked in at build time with
+// -ldflags "-X github.com/ovila98/groot.defaultRoot=/opt/app".
+var defaultRoot string
+
+// Env var opting into root auto-detection at init. Supported strategies:
+//
+// - git: the nearest parent directory containing .git (see SetRootFromGit)
+//
+// - gomod: the nearest parent directory containing go.mod (see SetRootFromGoMod)
+const autodetectEnv = "GROOT_AUTODETECT"
+
+func init() {
+	seedRoot()
+}
+
+// seedRoot seeds the root at init unless the root env var is already set,
+// from the strategy named by GROOT_AUTODETECT if any, else from defaultRoot.
+// Precedence is: explicit SetRoot* calls > root env var > GROOT_AUTODETECT >
+// defaultRoot. A failed detection or invalid defaultRoot is silent and leaves
+// the root unset.
+func seedRoot() {
+	if os.Getenv(grootEnv) != "" {
+		return
+	}
+	if autodetectRoot(os.Getenv(autodetectEnv)) {
+		return
+	}
+	seedDefaultRoot()
+}
+
+// seedDefaultRoot sets the root to defaultRoot once validated the way
+// SetRootFromPath does: relative to the project directory, and an existing
+// directory. A missing or invalid build-time root is skipped silently, since
+// init cannot report errors and the root simply stays unset.
+func seedDefaultRoot() {
+	path := strings.TrimSpace(defaultRoot)
+	if path == "" {
+		return
+	}
+	path, err := resolveFromProjectDir(path)
+	if err != nil {
+		return
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+		return
+	}
+	setRootPath(path, SourceBuild)
+}
+
+// autodetectRoot sets the root with the named strategy, searching from the
+// executable directory (unless temporary) then the working directory.
+// The main file is unknown while packages initialize, hence not searched from.
+// Reports whether the root was set.
+func autodetectRoot(strategy string) bool {
+	var find func(startPath string) string
+	var source RootSource
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "git":
+		find, source = FindGitRootFrom, SourceGit
+	case "gomod":
+		find, source = FindGoModRootFrom, SourceMarker
+	default:
+		return false
+	}
+
+	var dirs []string
+	if !IsTemporary() {
+		if dir, err := GetExecutableDir(); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+	}
+	for _, dir := range dirs {
+		if root := find(dir); root != "" {
+			return setRootPath(root, source) == nil
+		}
+	}
+	return false
+}
+
 // SetGrootKey changes the environment variable key used to store the root path.
-// Returns error if key is empty.
+// Returns error if key is empty and ErrRootLocked if the root is locked.
 func SetGrootKey(key string) error {
 	key = strings.TrimSpace(key)
 	if key == "" {
 		return ers.New("key cannot be empty")
 	}
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	if rootLocked {
+		return ers.Wrap(ErrRootLocked)
+	}
 	grootEnv = key
+	rootCached = false
+	rootGeneration++
 	return nil
 }
 
@@ -35,11 +131,25 @@ var ErrMissingEnvs = errors.New("missing env files")
 // ErrBadEnvsDefined indicates invalid environment filenames were provided
 var ErrBadEnvsDefined = errors.New("bad env files defined")
 
+// ErrNoEntryFile indicates no entry file was given to search for
+var ErrNoEntryFile = errors.New("entry file not defined")
+
+// ErrNoRootFound indicates no directory containing the entry file was found
+var ErrNoRootFound = errors.New("no root found")
+
+// ErrRootNotSet indicates the root has not been set
+var ErrRootNotSet = errors.New("root not set")
+
+// ErrNotDirectory indicates a path exists but is not a directory
+var ErrNotDirectory = errors.New("not a directory")
+
 // IterateThroughPath returns a slice of paths starting from the given path
 // up to the filesystem root. The returned paths are valid but may not exist.
+// The path is cleaned first ("." and ".." components and trailing separators
+// are resolved), so each returned path is clean and appears only once.
 // Absolute paths are recommended.
 func IterateThroughPath(path string) []string {
-	path = ensureCleanPath(path)
+	path = filepath.Clean(ensureCleanPath(path))
 
 	var paths []string
 	for path != filepath.Dir(path) {
@@ -55,7 +165,8 @@ func IterateThroughPath(path string) []string {
 // The root is set to the directory containing the first occurrence of entryFile,
 // searching upward from the current directory.
 //
-// Environment files are loaded from all directories up to root:
+// Environment files are loaded from every directory on the path from the
+// project directory up to root, both inclusive:
 //
 // - Only filenames should be provided (no paths)
 //
@@ -75,13 +186,306 @@ func IterateThroughPath(path string) []string {
 //
 // - ErrBadEnvsDefined if invalid env filenames provided
 func SetRoot(entryFile string, envFiles ...string) error {
-	entryFile = strings.TrimSpace(entryFile)
-	if entryFile == "" {
-		return ers.New("entry file not defined")
+	_, err := setRoot([]string{entryFile}, envFiles, nil)
+	return ers.Wrap(err)
+}
+
+// SetRootAnyOf behaves like SetRoot but stops at the first directory, searching
+// upward, containing any of entryFiles. When a directory contains several of
+// them, the first in entryFiles order is the matched entry file (and is the
+// one loaded if it ends in .env). Env loading is identical to SetRoot.
+func SetRootAnyOf(entryFiles []string, envFiles ...string) error {
+	_, err := setRoot(entryFiles, envFiles, nil)
+	return ers.Wrap(err)
+}
+
+// SetRootResult describes the outcome of a SetRootWithResult call.
+type SetRootResult struct {
+	// Root is the established root directory
+	Root string
+	// EnvFiles lists the env files loaded, in load order
+	EnvFiles []string
+	// EmptyEnvFiles lists loaded env files defining no variables (empty or
+	// comments only). They still satisfy required env files.
+	EmptyEnvFiles []string
+	// SkippedEnvFiles lists env files not loaded because their condition
+	// (see EnvOptions.Conditions) was false
+	SkippedEnvFiles []string
+	// ExcludedEnvFiles lists matching env files found in directories above
+	// root. They are never loaded and are reported for diagnosis only.
+	ExcludedEnvFiles []string
+	// NormalizedEnvFiles lists loaded env files whose UTF-8 BOM or CRLF line
+	// endings were fixed before parsing, typically edited on Windows
+	NormalizedEnvFiles []string
+	// NoOp reports that the call repeated the previous one and was skipped
+	// (see SetIdempotent)
+	NoOp bool
+}
+
+// SetRootWithResult behaves like SetRoot and also reports what was loaded.
+func SetRootWithResult(entryFile string, envFiles ...string) (SetRootResult, error) {
+	result, err := setRoot([]string{entryFile}, envFiles, nil)
+	return result, ers.Wrap(err)
+}
+
+// SetRootWithEnvPaths behaves like SetRoot but also accepts env files given as
+// paths relative to each searched directory (e.g. "config/app.env").
+//
+// envFiles are bare filenames, as in SetRoot: entries containing a separator
+// are ignored. envPaths are relative paths joined to every directory of the
+// upward search before matching: absolute paths and paths escaping the
+// searched directory (via "..") are ignored.
+func SetRootWithEnvPaths(entryFile string, envFiles []string, envPaths ...string) error {
+	_, err := setRoot([]string{entryFile}, envFiles, envPaths)
+	return ers.Wrap(err)
+}
+
+// setRoot implements SetRoot for any of several entry files and for both bare
+// env filenames and relative env paths.
+func setRoot(entryFiles []string, envFiles []string, envPaths []string) (SetRootResult, error) {
+	call := setRootCall{
+		call:              fmt.Sprintf("%q %q %q", entryFiles, envFiles, envPaths),
+		optionsGeneration: envOptionsGeneration.Load(),
+	}
+	// The lock only covers the idempotency decision: env loading runs OnEnvSet
+	// callbacks, which may call SetRoot or SetIdempotent themselves
+	lastSetRootMu.Lock()
+	if idempotentSetRoot && lastSetRoot.call == call.call && lastSetRoot.optionsGeneration == call.optionsGeneration {
+		rootMu.RLock()
+		unchanged := lastSetRoot.generation == rootGeneration
+		rootMu.RUnlock()
+		if unchanged {
+			result := lastSetRoot.result
+			lastSetRootMu.Unlock()
+			result.NoOp = true
+			return result, nil
+		}
+	}
+	lastSetRoot = setRootCall{}
+	lastSetRootMu.Unlock()
+
+	var result SetRootResult
+	d, err := discoverRoot(entryFiles, envFiles, envPaths)
+	if err != nil {
+		return result, ers.Wrap(err)
+	}
+	result.Root = d.root
+	result.ExcludedEnvFiles = d.excludedEnvPaths
+	if err := setRootPath(d.root, SourceEntryFile); err != nil {
+		return result, ers.Wrap(err)
+	}
+
+	if err := d.checkEnvs(); err != nil {
+		return result, ers.Wrap(err)
+	}
+	if len(d.envPaths) > 0 {
+		err := loadEnvFiles(&result, d.envPaths)
+		if err != nil {
+			return result, ers.Wrap(err)
+		}
+	}
+
+	rootMu.RLock()
+	call.generation = rootGeneration
+	rootMu.RUnlock()
+	call.result = result
+	lastSetRootMu.Lock()
+	lastSetRoot = call
+	lastSetRootMu.Unlock()
+	return result, nil
+}
+
+// setRootCall records a successful setRoot call for SetIdempotent
+type setRootCall struct {
+	// call identifies the arguments
+	call string
+	// optionsGeneration identifies the env options in effect
+	optionsGeneration uint64
+	// generation is the root generation right after the call
+	generation uint64
+	result     SetRootResult
+}
+
+// Idempotent SetRoot state, guarded by lastSetRootMu
+var (
+	lastSetRootMu     sync.Mutex
+	idempotentSetRoot bool
+	lastSetRoot       setRootCall
+)
+
+// SetIdempotent makes SetRoot and its entry file variants skip calls repeating
+// the arguments of the last successful one while neither the root nor the env
+// options (see SetEnvOptions) have changed since, so env files are not
+// reloaded and OnEnvSet callbacks not repeated.
+// The skipped call returns the previous result with NoOp set. Changes made to
+// the files on disk meanwhile are not detected. Disabled by default.
+func SetIdempotent(enabled bool) {
+	lastSetRootMu.Lock()
+	defer lastSetRootMu.Unlock()
+	idempotentSetRoot = enabled
+}
+
+// rootDiscovery is the outcome of the upward search done by discoverRoot.
+type rootDiscovery struct {
+	// root directory, containing entryFile
+	root      string
+	entryFile string
+	// cleaned env names requested and whether any was defined at all
+	envNames    []string
+	definedEnvs bool
+	// env files to load in order, and the env names they satisfy
+	envPaths      []string
+	foundEnvNames map[string]struct{}
+	// env files matching envNames found above root
+	excludedEnvPaths []string
+}
+
+// Whether the upward search fails on unreadable directories, see SetStrictSearch
+var strictSearch bool
+
+// SetStrictSearch controls how the upward search of SetRoot and its entry file
+// variants treats directories it is not permitted to read, e.g. a restricted
+// home directory above the project when running as a low-privilege user. By
+// default they are skipped as if they contained no match. When strict, the
+// search fails with the permission error instead.
+func SetStrictSearch(enabled bool) {
+	strictSearch = enabled
+}
+
+// discoverRoot searches upward from the project directory for the first
+// directory containing any of entryFiles, collecting env files on the way.
+// It does not change the root or the environment.
+func discoverRoot(entryFiles []string, envFiles []string, envPaths []string) (rootDiscovery, error) {
+	var d rootDiscovery
+	cleanEntryFiles := make([]string, 0, len(entryFiles))
+	for _, entryFile := range entryFiles {
+		if entryFile = strings.TrimSpace(entryFile); entryFile != "" {
+			cleanEntryFiles = append(cleanEntryFiles, entryFile)
+		}
+	}
+	if len(cleanEntryFiles) == 0 {
+		return d, ers.Wrap(ErrNoEntryFile)
+	}
+	d.definedEnvs = strings.TrimSpace(strings.Join(envFiles, "")+strings.Join(envPaths, "")) != ""
+	d.envNames = append(cleanFilenames(envFiles...), cleanRelPaths(envPaths...)...)
+	if d.definedEnvs && len(d.envNames) == 0 {
+		return d, ers.Wrap(ErrBadEnvsDefined)
+	}
+
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return d, ers.Wrap(err)
+	}
+
+	// The walk stops at the root, so env files only come from directories
+	// between the project dir and root
+	d.envPaths = make([]string, 0)
+	d.foundEnvNames = make(map[string]struct{})
+	searchedNames := append(append([]string(nil), d.envNames...), cleanEntryFiles...)
+	for _, path := range IterateThroughPath(projectDir) {
+		if strictSearch {
+			if err := checkSearchable(path, searchedNames); err != nil {
+				return d, ers.Wrap(err)
+			}
+		}
+		for _, name := range d.envNames {
+			if envOptions.OSVariants {
+				// Variants are loaded first so they take precedence
+				found, err := findFiles(path, []string{osVariant(name)})
+				if err != nil {
+					return d, ers.Wrap(err)
+				}
+				d.envPaths = append(d.envPaths, found...)
+			}
+			found, err := findFiles(path, []string{name})
+			if err != nil {
+				return d, ers.Wrap(err)
+			}
+			if len(found) > 0 {
+				d.foundEnvNames[name] = struct{}{}
+			}
+			d.envPaths = append(d.envPaths, found...)
+		}
+		d.entryFile = findEntryFile(path, cleanEntryFiles)
+		if d.entryFile != "" {
+			d.root = path
+			break
+		}
+	}
+
+	if d.root == "" {
+		return d, ers.Wrap(ErrNoRootFound)
+	}
+	d.excludedEnvPaths = findEnvFilesAbove(d.root, d.envNames)
+
+	if strings.HasSuffix(d.entryFile, ".env") {
+		if envOptions.OSVariants {
+			variant := filepath.Join(d.root, osVariant(d.entryFile))
+			if f, err := os.Stat(variant); err == nil && !f.IsDir() {
+				d.envPaths = append(d.envPaths, variant)
+			}
+		}
+		d.envPaths = append(d.envPaths, filepath.Join(d.root, d.entryFile))
+		d.foundEnvNames[d.entryFile] = struct{}{}
+	}
+	// The same file can be reached twice through symlinked directories
+	d.envPaths = dedupeResolvedPaths(d.envPaths)
+
+	return d, nil
+}
+
+// checkEnvs returns ErrNoEnvDefined if no env file was requested nor found
+// (the entry file counts if it ends in .env) and ErrMissingEnvs if a requested
+// env file was not found.
+func (d rootDiscovery) checkEnvs() error {
+	if !d.definedEnvs {
+		if len(d.envPaths) == 0 {
+			return ers.Wrap(ErrNoEnvDefined)
+		}
+		return nil
+	}
+
+	// Check if each required env file was found
+	for _, name := range d.envNames {
+		if _, exists := d.foundEnvNames[name]; !exists {
+			return ers.Wrap(ErrMissingEnvs)
+		}
+	}
+	return nil
+}
+
+// findEnvFilesAbove returns the env files matching envNames in the directories
+// above root. Lookup errors are ignored since the result is only diagnostic.
+func findEnvFilesAbove(root string, envNames []string) []string {
+	var found []string
+	for _, path := range IterateThroughPath(root)[1:] {
+		files, err := findFiles(path, envNames)
+		if err == nil {
+			found = append(found, files...)
+		}
+	}
+	return found
+}
+
+// findEntryFile returns the first of entryFiles that is a regular file in dir,
+// or an empty string if none is.
+func findEntryFile(dir string, entryFiles []string) string {
+	for _, entryFile := range entryFiles {
+		if f, err := os.Stat(filepath.Join(dir, entryFile)); err == nil && !f.IsDir() {
+			return entryFile
+		}
 	}
-	definedEnvsFlag := strings.TrimSpace(strings.Join(envFiles, "")) != ""
-	cleanEnvFilenames := cleanFilenames(envFiles...)
-	if definedEnvsFlag && len(cleanEnvFilenames) == 0 {
+	return ""
+}
+
+// SetRootFromNearestEnv sets the root to the nearest directory, searching upward
+// from the project directory, containing any of the env files, and loads the
+// env files found there. The filesystem root itself is never considered.
+// Returns ErrBadEnvsDefined if no valid filename is given and ErrNoEnvDefined
+// if no directory contains the env files.
+func SetRootFromNearestEnv(envFiles ...string) error {
+	envNames := cleanFilenames(envFiles...)
+	if len(envNames) == 0 {
 		return ers.Wrap(ErrBadEnvsDefined)
 	}
 
@@ -90,154 +494,720 @@ func SetRoot(entryFile string, envFiles ...string) error {
 		return ers.Wrap(err)
 	}
 
-	foundEnvPaths := make([]string, 0)
-	for _, path := range IterateThroughPath(projectDir) {
-		found, err := findFiles(path, cleanEnvFilenames)
+	paths := IterateThroughPath(projectDir)
+	for _, path := range paths[:len(paths)-1] {
+		found, err := findFiles(path, envNames)
 		if err != nil {
 			return ers.Wrap(err)
 		}
-		foundEnvPaths = append(foundEnvPaths, found...)
-		if f, err := os.Stat(filepath.Join(path, entryFile)); err == nil && !f.IsDir() {
-			os.Setenv(grootEnv, path)
+		if len(found) == 0 {
+			continue
+		}
+		if err := setRootPath(path, SourceEntryFile); err != nil {
+			return ers.Wrap(err)
+		}
+		var result SetRootResult
+		return ers.Wrap(loadEnvFiles(&result, found))
+	}
+	return ers.Wrap(ErrNoEnvDefined)
+}
+
+// SetRootNoEnv sets the project root without requiring environment files.
+// Ignores ErrNoEnvDefined and returns other errors.
+func SetRootNoEnv(entryFile string) error {
+	err := SetRoot(entryFile)
+	if errors.Is(err, ErrNoEnvDefined) {
+		return nil
+	}
+	return ers.Wrap(err)
+}
+
+// SetRootFromEnv sets the root directory and loads the entry file as an env file.
+func SetRootFromEnv(entryFile string) error {
+	err := SetRoot(entryFile, entryFile)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	return nil
+}
+
+// SetRootFromGit sets the root to the nearest parent git repository.
+// Falls back to the working directory if the project dir cannot be determined.
+func SetRootFromGit() error {
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root := FindGitRootFrom(startDir)
+	if root == "" {
+		return ers.New("no git root found")
+	}
+	return setRootPath(root, SourceGit)
+}
+
+// SetRootFromTest sets the root to the directory of the calling _test.go file.
+// Returns error if not called directly from a _test.go file.
+func SetRootFromTest() error {
+	_, file, _, ok := runtime.Caller(1)
+	if !ok || !strings.HasSuffix(file, "_test.go") {
+		return ers.New("not called from a _test.go file")
+	}
+	return setRootPath(filepath.Dir(file), SourceTest)
+}
+
+// SetRootFromTestdata sets the root to the nearest directory containing a
+// testdata directory, searching upward from the caller's source file (or the
+// working directory if it is unknown), so fixtures are found whatever the
+// working directory of the test runner.
+// Returns error if no testdata directory is found.
+func SetRootFromTestdata() error {
+	startDir := ""
+	if _, file, _, ok := runtime.Caller(1); ok && filepath.IsAbs(file) {
+		startDir = filepath.Dir(file)
+	} else {
+		wd, err := os.Getwd()
+		if err != nil {
+			return ers.Wrap(err)
+		}
+		startDir = wd
+	}
+	root, err := findRootFrom(startDir, func(dir string) (bool, error) {
+		fi, err := os.Stat(filepath.Join(dir, "testdata"))
+		return err == nil && fi.IsDir(), nil
+	})
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if root == "" {
+		return ers.New("no testdata directory found above %s", startDir)
+	}
+	return setRootPath(root, SourceTest)
+}
+
+// SetRootFromPlugin sets the root to the source directory of symbol, a function
+// defined in a Go plugin (e.g. looked up with plugin.Lookup). Use it when code
+// runs inside a plugin, where GetProjectDir reports the host binary instead.
+// Like GetMainFile, this relies on the source path recorded at build time.
+// Go plugins are not supported on Windows.
+// Returns error if symbol is not a function or its file cannot be determined.
+func SetRootFromPlugin(symbol any) error {
+	v := reflect.ValueOf(symbol)
+	if v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Func {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ers.New("plugin symbol must be a function")
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ers.New("plugin symbol function not found")
+	}
+	file, _ := fn.FileLine(fn.Entry())
+	if !strings.HasSuffix(file, ".go") {
+		return ers.New("plugin symbol source file not found")
+	}
+	return setRootPath(filepath.Dir(file), SourcePlugin)
+}
+
+// SetRootFromPredicate sets the root to the first directory, searching upward
+// from the project directory, for which pred returns true.
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns error if pred fails for a directory or no directory matches.
+func SetRootFromPredicate(pred func(dir string) (bool, error)) error {
+	if pred == nil {
+		return ers.New("predicate cannot be nil")
+	}
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root, err := findRootFrom(startDir, pred)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if root == "" {
+		return ers.Wrap(ErrNoRootFound)
+	}
+	return setRootPath(root, SourcePredicate)
+}
+
+// SetRootFromMarkerFunc sets the root to the first directory, searching upward
+// from the project directory, containing marker for which validate returns true.
+// validate receives the path of the marker file (e.g. to check the module
+// declared by a go.mod). Directories whose marker does not validate are skipped.
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns error if validate fails or no directory matches.
+func SetRootFromMarkerFunc(marker string, validate func(path string) (bool, error)) error {
+	marker = strings.TrimSpace(marker)
+	if marker == "" {
+		return ers.New("marker cannot be empty")
+	}
+	if validate == nil {
+		return ers.New("validate cannot be nil")
+	}
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root, err := findRootFrom(startDir, func(dir string) (bool, error) {
+		path := filepath.Join(dir, marker)
+		if _, err := os.Stat(path); err != nil {
+			return false, nil
+		}
+		return validate(path)
+	})
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if root == "" {
+		return ers.New("no valid %s found", marker)
+	}
+	return setRootPath(root, SourceMarker)
+}
+
+// ErrSentinelNotFound indicates no sentinel file was found
+var ErrSentinelNotFound = errors.New("sentinel not found")
+
+// ProjectRootSentinel is the conventional sentinel file marking a project root.
+// It may be empty.
+const ProjectRootSentinel = ".project-root"
+
+// SetRootFromSentinel sets the root to the nearest parent directory containing
+// the sentinel file filename, or ProjectRootSentinel if filename is empty.
+// Dropping a sentinel overrides heuristic detection in tricky layouts.
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrSentinelNotFound if none found.
+func SetRootFromSentinel(filename string) error {
+	filename = strings.TrimSpace(filename)
+	if filename == "" {
+		filename = ProjectRootSentinel
+	}
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root, err := findRootFrom(startDir, func(dir string) (bool, error) {
+		return findEntryFile(dir, []string{filename}) != "", nil
+	})
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if root == "" {
+		return ers.Wrapf(ErrSentinelNotFound, "%s", filename)
+	}
+	return setRootPath(root, SourceMarker)
+}
+
+// ErrNoWorkspaceFound indicates no Bazel or Buck workspace marker was found
+var ErrNoWorkspaceFound = errors.New("no workspace found")
+
+// Files marking a Bazel or Buck workspace root
+var workspaceMarkers = []string{"WORKSPACE", "WORKSPACE.bazel", "MODULE.bazel", ".buckconfig"}
+
+// SetRootFromWorkspace sets the root to the nearest parent directory containing
+// a Bazel (WORKSPACE, WORKSPACE.bazel, MODULE.bazel) or Buck (.buckconfig) marker.
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrNoWorkspaceFound if none found.
+func SetRootFromWorkspace() error {
+	return ers.Wrap(setRootFromMarkers(workspaceMarkers, ErrNoWorkspaceFound))
+}
+
+// ErrNoLockfileFound indicates no recognized dependency lockfile was found
+var ErrNoLockfileFound = errors.New("no lockfile found")
+
+// Dependency lockfiles recognized by SetRootFromLockfile
+var lockfileMarkers = []string{"go.sum", "Cargo.lock", "package-lock.json", "poetry.lock", "Gemfile.lock"}
+
+// Lockfiles returns the dependency lockfile names recognized by
+// SetRootFromLockfile: go.sum, Cargo.lock, package-lock.json, poetry.lock and
+// Gemfile.lock.
+func Lockfiles() []string {
+	return append([]string(nil), lockfileMarkers...)
+}
+
+// SetRootFromLockfile sets the root to the nearest parent directory containing
+// any recognized dependency lockfile (see Lockfiles), a language-agnostic
+// project root signal.
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrNoLockfileFound if none found.
+func SetRootFromLockfile() error {
+	return ers.Wrap(setRootFromMarkers(lockfileMarkers, ErrNoLockfileFound))
+}
+
+// ErrNoDeployManifestFound indicates no deployment manifest was found
+var ErrNoDeployManifestFound = errors.New("no deployment manifest found")
+
+// Files sitting at the root of PaaS deployments
+var deployManifestMarkers = []string{"Procfile", "app.yaml", "fly.toml", "render.yaml"}
+
+// SetRootFromDeployManifest sets the root to the nearest parent directory
+// containing a deployment manifest: Procfile (Heroku and similar), app.yaml
+// (App Engine), fly.toml (Fly.io) or render.yaml (Render). Useful for deployed
+// apps that are not git checkouts at runtime.
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrNoDeployManifestFound if none found.
+func SetRootFromDeployManifest() error {
+	return ers.Wrap(setRootFromMarkers(deployManifestMarkers, ErrNoDeployManifestFound))
+}
+
+// setRootFromMarkers sets the root to the nearest directory containing any of
+// markers, returning notFound if there is none.
+func setRootFromMarkers(markers []string, notFound error) error {
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root, err := findRootFrom(startDir, func(dir string) (bool, error) {
+		return hasAnyFile(dir, markers), nil
+	})
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if root == "" {
+		return ers.Wrap(notFound)
+	}
+	return setRootPath(root, SourceMarker)
+}
+
+// SetRootFromGopath sets the root to $GOPATH/src/<importPath> for legacy
+// GOPATH-mode projects. Every GOPATH entry is tried in order.
+// Returns error if importPath is empty or not found under any GOPATH entry.
+func SetRootFromGopath(importPath string) error {
+	importPath = strings.Trim(strings.TrimSpace(importPath), "/")
+	if importPath == "" {
+		return ers.New("import path cannot be empty")
+	}
+
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		if gopath == "" {
+			continue
+		}
+		path := filepath.Join(gopath, "src", filepath.FromSlash(importPath))
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			return setRootPath(path, SourcePath)
+		}
+	}
+	return ers.New("import path %q not found in GOPATH", importPath)
+}
+
+// SetRootFromPath sets the root directory from the given path.
+// If path is absolute, sets root to that path.
+// If path is relative, resolves it from the project directory.
+// Returns error if path is empty, invalid, or does not exist.
+func SetRootFromPath(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ers.New("path cannot be empty")
+	}
+
+	path, err := resolveFromProjectDir(path)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+
+	if !fi.IsDir() {
+		return ers.New("path is not a directory")
+	}
+
+	return setRootPath(path, SourcePath)
+}
+
+// SetRootFromPathCreate behaves like SetRootFromPath but creates the directory,
+// including parents, with perm if it does not exist.
+// Returns error if path is empty, invalid, or exists but is not a directory,
+// and ErrReadOnlyRoot if it would have to be created in read-only mode.
+func SetRootFromPathCreate(path string, perm os.FileMode) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ers.New("path cannot be empty")
+	}
+
+	path, err := resolveFromProjectDir(path)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+
+	fi, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if IsReadOnly() {
+			return ers.Wrapf(ErrReadOnlyRoot, "cannot create %s", path)
+		}
+		if err := os.MkdirAll(path, perm); err != nil {
+			return ers.Wrap(err)
+		}
+	case err != nil:
+		return ers.Wrap(err)
+	case !fi.IsDir():
+		return ers.New("path is not a directory")
+	}
+
+	return setRootPath(path, SourcePath)
+}
+
+// resolveFromProjectDir joins a relative path to the project directory.
+// Absolute paths are returned unchanged.
+func resolveFromProjectDir(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return filepath.Join(projectDir, path), nil
+}
+
+// SetRootFromParent sets the root to the directory levels above the project
+// directory (0 being the project directory itself), for fixed layouts where
+// marker search is unnecessary.
+// Returns error if levels is negative or goes above the filesystem root, or
+// if the resulting directory does not exist.
+func SetRootFromParent(levels int) error {
+	if levels < 0 {
+		return ers.New("levels cannot be negative, got %d", levels)
+	}
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	path := filepath.Clean(projectDir)
+	for i := 0; i < levels; i++ {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return ers.New("cannot go %d levels up from %s, filesystem root reached after %d", levels, projectDir, i)
+		}
+		path = parent
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if !fi.IsDir() {
+		return ers.Wrapf(ErrNotDirectory, "%s", path)
+	}
+	return setRootPath(path, SourcePath)
+}
+
+// SetRootFromArgs looks for --<flagName>=path or --<flagName> path in args and,
+// if found, sets the root from that path as SetRootFromPath does.
+// Scanning stops at a "--" argument. args is never modified.
+// Returns whether the root was set.
+func SetRootFromArgs(args []string, flagName string) (bool, error) {
+	flagName = strings.TrimLeft(strings.TrimSpace(flagName), "-")
+	if flagName == "" {
+		return false, ers.New("flag name cannot be empty")
+	}
+	flag := "--" + flagName
+
+	for i, arg := range args {
+		if arg == "--" {
 			break
 		}
+		path, found := "", false
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			path, found = value, true
+		} else if arg == flag {
+			if i+1 >= len(args) {
+				return false, ers.New("flag %s requires a path", flag)
+			}
+			path, found = args[i+1], true
+		}
+		if found {
+			if err := SetRootFromPath(path); err != nil {
+				return false, ers.Wrap(err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetRoot returns the current project root directory from the root store.
+// Returns empty string if not set or if the store cannot provide it.
+//
+// The root is cached after the first lookup and refreshed by every SetRoot*,
+// ClearRoot, SetStore and SetGrootKey call. Changes made to the store outside
+// of groot (e.g. os.Setenv on the root key) are not seen until then, unless
+// no root was found: an empty root is never cached.
+// If no root is set, it is resolved with the fallback chain, if any (see
+// SetFallbackChain).
+func GetRoot() string {
+	root, _ := ResolveRoot()
+	return root
+}
+
+// FromRoot joins the given path elements with the root directory.
+// If root is not set or first path is absolute, joins paths without root.
+func FromRoot(path ...string) string {
+	joined, _ := FromRootChecked(path...)
+	return joined
+}
+
+// FromRootChecked joins the given path elements as FromRoot does and reports
+// whether the root was applied, i.e. false when root is not set or the first
+// path is absolute.
+func FromRootChecked(path ...string) (joined string, rootApplied bool) {
+	root := GetRoot()
+	rootApplied = root != "" && (len(path) == 0 || !filepath.IsAbs(path[0]))
+	return FromRootWith(root, path...), rootApplied
+}
+
+// CountEntryMatches returns how many directories from the project directory up to
+// the filesystem root contain entryFile.
+// This is a diagnostic for ambiguous layouts and does not affect SetRoot, which
+// always stops at the nearest match.
+func CountEntryMatches(entryFile string) (int, error) {
+	entryFile = strings.TrimSpace(entryFile)
+	if entryFile == "" {
+		return 0, ers.Wrap(ErrNoEntryFile)
+	}
+
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return 0, ers.Wrap(err)
+	}
+
+	count := 0
+	for _, path := range IterateThroughPath(projectDir) {
+		if f, err := os.Stat(filepath.Join(path, entryFile)); err == nil && !f.IsDir() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Diagnose returns a human-readable report of how SetRoot searches for
+// entryFile: the starting directory and, for each directory checked, whether
+// the entry file is present, missing or a directory. It follows the same
+// search as SetRoot and does not change the root.
+func Diagnose(entryFile string) string {
+	var report strings.Builder
+	entryFile = strings.TrimSpace(entryFile)
+	if entryFile == "" {
+		return "entry file not defined\n"
+	}
+	fmt.Fprintf(&report, "entry file: %s\n", entryFile)
+
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		fmt.Fprintf(&report, "cannot determine project dir: %v\n", err)
+		return report.String()
+	}
+	fmt.Fprintf(&report, "starting directory: %s\n", projectDir)
+
+	for _, path := range IterateThroughPath(projectDir) {
+		if findEntryFile(path, []string{entryFile}) != "" {
+			fmt.Fprintf(&report, "  %s: found, root is here\n", path)
+			return report.String()
+		}
+		fi, err := os.Stat(filepath.Join(path, entryFile))
+		switch {
+		case err == nil && fi.IsDir():
+			fmt.Fprintf(&report, "  %s: present but is a directory\n", path)
+		case err == nil || errors.Is(err, os.ErrNotExist):
+			fmt.Fprintf(&report, "  %s: not found\n", path)
+		default:
+			fmt.Fprintf(&report, "  %s: cannot check: %v\n", path, err)
+		}
 	}
-	root := os.Getenv(grootEnv)
+	report.WriteString("no root found\n")
+	return report.String()
+}
 
+// FromScopedRoot joins the given path elements with the base subdirectory of root.
+// Returns error if root is not set or if base is absolute, volume-relative
+// (e.g. C:dir on Windows) or escapes root.
+func FromScopedRoot(base string, path ...string) (string, error) {
+	root := GetRoot()
 	if root == "" {
-		return ers.New("no root found")
+		return "", ers.Wrap(ErrRootNotSet)
+	}
+
+	base = filepath.Clean(ensureCleanPath(base))
+	if escapesRoot(base) {
+		return "", ers.New("scope base %q escapes root", base)
+	}
+
+	return filepath.Join(root, base, filepath.Join(path...)), nil
+}
+
+// Git roots found by FindGitRootFrom, keyed by searched directory.
+var (
+	gitRootCacheMu sync.RWMutex
+	gitRootCache   = make(map[string]string)
+)
+
+// FindGitRootFrom locates the nearest parent git repository from startPath.
+// Returns empty string if none found.
+//
+// Relative paths are resolved from the working directory, and the returned
+// root is absolute. Results are cached per directory, so repeated queries for
+// files under the same repository are cheap. Use ClearGitRootCache after
+// creating or removing repositories.
+func FindGitRootFrom(startPath string) string {
+	startDir, err := absCleanPath(startPath)
+	if err != nil {
+		return ""
 	}
-
-	if strings.HasSuffix(entryFile, ".env") {
-		foundEnvPaths = append(foundEnvPaths, filepath.Join(root, entryFile))
+	if f, err := os.Stat(startDir); err == nil && !f.IsDir() {
+		startDir = filepath.Dir(startDir)
 	}
 
-	if len(envFiles) == 0 || !definedEnvsFlag {
-		if len(foundEnvPaths) != 0 {
-			// If no env files are provided and entryFile is *.env then use it
-			err := godotenv.Load(foundEnvPaths...)
-			return ers.Wrap(err)
-		}
-		return ers.Wrap(ErrNoEnvDefined)
+	gitRootCacheMu.RLock()
+	root, ok := gitRootCache[startDir]
+	gitRootCacheMu.RUnlock()
+	if ok {
+		return root
 	}
 
-	if definedEnvsFlag {
-		// Convert foundEnvPaths to just filenames for comparison
-		foundFilenames := make(map[string]struct{})
-		for _, path := range foundEnvPaths {
-			foundFilenames[filepath.Base(path)] = struct{}{}
-		}
-
-		// Check if each required env file was found
-		for _, requiredFile := range cleanEnvFilenames {
-			if _, exists := foundFilenames[requiredFile]; !exists {
-				return ers.Wrap(ErrMissingEnvs)
-			}
+	var visited []string
+	for _, path := range IterateThroughPath(startDir) {
+		gitRootCacheMu.RLock()
+		root, ok = gitRootCache[path]
+		gitRootCacheMu.RUnlock()
+		if ok {
+			break
 		}
-	}
-	if len(foundEnvPaths) > 0 {
-		err := godotenv.Load(foundEnvPaths...)
-		if err != nil {
-			return ers.Wrap(err)
+		visited = append(visited, path)
+		if f, err := os.Stat(filepath.Join(path, ".git")); err == nil && f.IsDir() {
+			root = path
+			break
 		}
 	}
 
-	return nil
+	// Every visited directory shares the same nearest git root
+	gitRootCacheMu.Lock()
+	for _, path := range visited {
+		gitRootCache[path] = root
+	}
+	gitRootCacheMu.Unlock()
+	return root
 }
 
-// SetRootNoEnv sets the project root without requiring environment files.
-// Ignores ErrNoEnvDefined and returns other errors.
-func SetRootNoEnv(entryFile string) error {
-	err := SetRoot(entryFile)
-	if errors.Is(err, ErrNoEnvDefined) {
-		return nil
-	}
-	return ers.Wrap(err)
+// ClearGitRootCache empties the cache used by FindGitRootFrom.
+func ClearGitRootCache() {
+	gitRootCacheMu.Lock()
+	defer gitRootCacheMu.Unlock()
+	gitRootCache = make(map[string]string)
 }
 
-// SetRootFromEnv sets the root directory and loads the entry file as an env file.
-func SetRootFromEnv(entryFile string) error {
-	err := SetRoot(entryFile, entryFile)
-	if err != nil {
-		return ers.Wrap(err)
+// ErrNoGoWorkFound indicates no go.work file was found
+var ErrNoGoWorkFound = errors.New("no go.work found")
+
+// ErrNoGoModFound indicates no go.mod file was found
+var ErrNoGoModFound = errors.New("no go.mod found")
+
+// FindGoModRootFrom locates the nearest parent directory of startPath
+// containing a go.mod file.
+// Returns empty string if none found.
+func FindGoModRootFrom(startPath string) string {
+	for _, path := range IterateThroughPath(startPath) {
+		if f, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && !f.IsDir() {
+			return path
+		}
 	}
-	return nil
+	return ""
 }
 
-// SetRootFromGit sets the root to the nearest parent git repository.
-func SetRootFromGit() error {
-	projectDir, err := GetProjectDir()
+// SetRootFromGoMod sets the root to the nearest parent Go module (go.mod).
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrNoGoModFound if none found.
+func SetRootFromGoMod() error {
+	startDir, err := getSearchDir()
 	if err != nil {
 		return ers.Wrap(err)
 	}
-	root := FindGitRootFrom(projectDir)
+	root := FindGoModRootFrom(startDir)
 	if root == "" {
-		return ers.New("no git root found")
+		return ers.Wrap(ErrNoGoModFound)
 	}
-	os.Setenv(grootEnv, root)
-	return nil
+	return setRootPath(root, SourceMarker)
 }
 
-// SetRootFromPath sets the root directory from the given path.
-// If path is absolute, sets root to that path.
-// If path is relative, resolves it from the project directory.
-// Returns error if path is empty, invalid, or does not exist.
-func SetRootFromPath(path string) error {
-	path = strings.TrimSpace(path)
-	if path == "" {
-		return ers.New("path cannot be empty")
+// SetRootFromMainModule sets the root to the directory of the main module's
+// go.mod, as named by debug.ReadBuildInfo, searching upward from the main
+// package directory. Unlike SetRootFromGoMod it skips the go.mod files of
+// other modules on the way, e.g. when groot is used from a library.
+//
+// Build info is missing from binaries built without module support, and the
+// main module's sources are usually absent where binaries are deployed, so
+// this is mostly useful with 'go run' and 'go test'.
+// Returns error if build info is unavailable or the main module's go.mod is
+// not found.
+func SetRootFromMainModule() error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "" || info.Main.Path == "command-line-arguments" {
+		return ers.New("main module unknown: build info unavailable")
 	}
-
-	if !filepath.IsAbs(path) {
-		projectDir, err := GetProjectDir()
-		if err != nil {
-			return ers.Wrap(err)
-		}
-		path = filepath.Join(projectDir, path)
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
 	}
-
-	fi, err := os.Stat(path)
+	root, err := findRootFrom(startDir, func(dir string) (bool, error) {
+		return goModulePath(filepath.Join(dir, "go.mod")) == info.Main.Path, nil
+	})
 	if err != nil {
 		return ers.Wrap(err)
 	}
-
-	if !fi.IsDir() {
-		return ers.New("path is not a directory")
+	if root == "" {
+		return ers.Wrapf(ErrNoGoModFound, "module %s above %s", info.Main.Path, startDir)
 	}
-
-	os.Setenv(grootEnv, path)
-	return nil
+	return setRootPath(root, SourceMarker)
 }
 
-// GetRoot returns the current project root directory.
-// Returns empty string if not set.
-func GetRoot() string {
-	return os.Getenv(grootEnv)
-}
-
-// FromRoot joins the given path elements with the root directory.
-// If root is not set or first path is absolute, joins paths without root.
-func FromRoot(path ...string) string {
-	root := GetRoot()
-	if root == "" || filepath.IsAbs(path[0]) {
-		return filepath.Join(path...)
+// goModulePath returns the module path declared by the go.mod file at path.
+// Returns empty string if the file cannot be read or declares no module.
+func goModulePath(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
 	}
-	return filepath.Join(root, filepath.Join(path...))
+	return ""
 }
 
-// FindGitRootFrom locates the nearest parent git repository from startPath.
+// FindGoWorkRootFrom locates the nearest parent directory of startPath
+// containing a go.work file.
 // Returns empty string if none found.
-func FindGitRootFrom(startPath string) string {
-	paths := IterateThroughPath(startPath)
-
-	for _, path := range paths {
-		if f, err := os.Stat(filepath.Join(path, ".git")); err == nil && f.IsDir() {
+func FindGoWorkRootFrom(startPath string) string {
+	for _, path := range IterateThroughPath(startPath) {
+		if f, err := os.Stat(filepath.Join(path, "go.work")); err == nil && !f.IsDir() {
 			return path
 		}
 	}
 	return ""
 }
 
+// SetRootFromGoWork sets the root to the nearest parent Go workspace (go.work).
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrNoGoWorkFound if none found.
+func SetRootFromGoWork() error {
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root := FindGoWorkRootFrom(startDir)
+	if root == "" {
+		return ers.Wrap(ErrNoGoWorkFound)
+	}
+	return setRootPath(root, SourceMarker)
+}
+
 func GetMainFile() (string, error) {
 	callFrame := 0
 	for {
@@ -257,17 +1227,66 @@ func GetMainFile() (string, error) {
 	return goFile, nil
 }
 
+// Resolves the path of the running executable.
+var executable = os.Executable
+
+// Resolves the path of the file containing main(), see GetMainFile.
+var mainFile = GetMainFile
+
+// executablePath returns the absolute path of the running executable with
+// symbolic links resolved, so a binary installed as a symlink (e.g. in
+// /usr/local/bin) reports its real location.
+func executablePath() (string, error) {
+	execPath, err := executable()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	execPath, err = filepath.Abs(execPath)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+	return execPath, nil
+}
+
+// GetExecutableDir returns the directory containing the running executable,
+// with symbolic links resolved.
+func GetExecutableDir() (string, error) {
+	execPath, err := executablePath()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return filepath.Dir(execPath), nil
+}
+
+// getSearchDir returns the project directory, falling back to the working
+// directory when it cannot be resolved (e.g. stripped binaries where the main
+// source file is unknown).
+func getSearchDir() (string, error) {
+	projectDir, err := GetProjectDir()
+	if err == nil {
+		return projectDir, nil
+	}
+	wd, wdErr := os.Getwd()
+	if wdErr != nil {
+		return "", ers.Wrap(err)
+	}
+	return wd, nil
+}
+
 // GetProjectDir returns either the directory containing the executable
 // or the directory containing the file containing main() depending on
 // calling context ('go run' or standalone executable).
 func GetProjectDir() (string, error) {
-	execPath, err := os.Executable()
+	execPath, err := executablePath()
 	if err != nil {
 		return "", ers.Wrap(err)
 	}
 	execDir := filepath.Dir(execPath)
 
-	goFile, err := GetMainFile()
+	goFile, err := mainFile()
 	if err != nil {
 		return "", ers.Wrap(err)
 	}
@@ -298,20 +1317,65 @@ func GetProjectDir() (string, error) {
 
 // IsRoot checks if the provided path is the project root directory
 func IsRoot(path string) bool {
-	root := GetRoot()
-	if root == "" {
+	cleanRoot, err := canonRoot()
+	if err != nil {
+		return false
+	}
+	cleanPath, err := CanonPath(path)
+	if err != nil {
 		return false
 	}
-	cleanPath := ensureCleanPath(path)
-	cleanRoot := ensureCleanPath(root)
 	return cleanPath == cleanRoot
 }
 
+// IsCWDRoot reports whether the working directory is the project root, e.g.
+// for CLIs behaving differently when run from the root.
+// Returns an error if root is not set or the working directory is unknown.
+func IsCWDRoot() (bool, error) {
+	cleanRoot, err := canonRoot()
+	if err != nil {
+		return false, ers.Wrap(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, ers.Wrap(err)
+	}
+	cleanWd, err := CanonPath(wd)
+	if err != nil {
+		return false, ers.Wrap(err)
+	}
+	return cleanWd == cleanRoot, nil
+}
+
 // IsTemporary checks wether the current execution context is temporary.
 // (i.e. if 'go run' has been called).
 func IsTemporary() bool {
-	executable, _ := os.Executable()
-	return strings.Contains(executable, "go-build")
+	execPath, _ := executable()
+	return IsTemporaryExecutable(execPath)
+}
+
+// IsTemporaryExecutable reports whether path looks like a temporary executable
+// built by 'go run' or 'go test': it lies in a go-build directory, under
+// os.TempDir(), or is a test binary (*.test, *.test.exe).
+func IsTemporaryExecutable(path string) bool {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return false
+	}
+	// Normalize both separator styles so any platform's paths can be checked
+	slashed := strings.ReplaceAll(path, "\\", "/")
+	if strings.Contains(slashed, "/go-build") || strings.HasPrefix(slashed, "go-build") {
+		return true
+	}
+	base := strings.ToLower(slashed[strings.LastIndex(slashed, "/")+1:])
+	if strings.HasSuffix(base, ".test") || strings.HasSuffix(base, ".test.exe") {
+		return true
+	}
+	tempDir := os.TempDir()
+	if runtime.GOOS == "windows" {
+		return isWithin(strings.ToLower(tempDir), strings.ToLower(path))
+	}
+	return isWithin(tempDir, path)
 }
 
 // GetRootParent returns the parent directory of the project root.
@@ -328,16 +1392,34 @@ func GetRootParent() string {
 	return parent
 }
 
+// ErrDifferentVolume indicates a path is on another volume than root (e.g.
+// another drive letter on Windows), so no relative path exists between them
+var ErrDifferentVolume = errors.New("path on a different volume than root")
+
 // GetRelativeToRoot returns the relative path from root to the given path.
-// Returns an error if root is not set or if path is not under root.
+// Returns an error if root is not set or if path is not under root, and
+// ErrDifferentVolume if path is on another volume, in which case callers may
+// fall back to the absolute path.
 func GetRelativeToRoot(path string) (string, error) {
-	root := GetRoot()
-	if root == "" {
-		return "", ers.New("root not set")
+	cleanRoot, err := canonRoot()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	rel, err := relFromCanonRoot(cleanRoot, path)
+	return rel, ers.Wrap(err)
+}
+
+// relFromCanonRoot returns the relative path from the canonical root
+// cleanRoot to path
+func relFromCanonRoot(cleanRoot, path string) (string, error) {
+	cleanPath, err := CanonPath(path)
+	if err != nil {
+		return "", ers.Wrap(err)
 	}
 
-	cleanPath := ensureCleanPath(path)
-	cleanRoot := ensureCleanPath(root)
+	if !strings.EqualFold(filepath.VolumeName(cleanRoot), filepath.VolumeName(cleanPath)) {
+		return "", ers.Wrapf(ErrDifferentVolume, "%s", path)
+	}
 
 	rel, err := filepath.Rel(cleanRoot, cleanPath)
 	if err != nil {
@@ -347,12 +1429,67 @@ func GetRelativeToRoot(path string) (string, error) {
 	return rel, nil
 }
 
+// GetRootSubdir returns the absolute path of the name subdirectory of root.
+// Returns ErrRootNotSet if root is not set, an error if name is empty, root
+// itself, absolute or escapes root, an error matching fs.ErrNotExist if the
+// subdirectory does not exist and ErrNotDirectory if it is not a directory.
+func GetRootSubdir(name string) (string, error) {
+	root, err := GetRootAbs()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return "", ers.New("subdirectory name cannot be empty")
+	}
+	name = filepath.Clean(ensureCleanPath(name))
+	if name == "." {
+		return "", ers.New("subdirectory name %q is root itself", name)
+	}
+	if escapesRoot(name) {
+		return "", ers.New("subdirectory %q escapes root", name)
+	}
+
+	path := filepath.Join(root, name)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	if !fi.IsDir() {
+		return "", ers.Wrapf(ErrNotDirectory, "%s", path)
+	}
+
+	return path, nil
+}
+
+// GetRootRelativeTo returns the relative path from base to root.
+// Returns an error if root is not set or if no relative path exists
+// (e.g. different drives on Windows).
+func GetRootRelativeTo(base string) (string, error) {
+	cleanRoot, err := canonRoot()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+
+	cleanBase, err := CanonPath(base)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+
+	rel, err := filepath.Rel(cleanBase, cleanRoot)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+
+	return rel, nil
+}
+
 // ListFilesFromRoot returns a slice of file paths matching the given pattern relative to root.
 // Pattern follows filepath.Glob syntax.
 func ListFilesFromRoot(pattern string) ([]string, error) {
 	root := GetRoot()
 	if root == "" {
-		return nil, ers.New("root not set")
+		return nil, ers.Wrap(ErrRootNotSet)
 	}
 
 	matches, err := filepath.Glob(filepath.Join(root, pattern))
@@ -363,15 +1500,43 @@ func ListFilesFromRoot(pattern string) ([]string, error) {
 	return matches, nil
 }
 
+// ListFilesFromRootMulti returns the sorted, deduplicated union of the file paths
+// matching each pattern relative to root.
+// Patterns follow filepath.Glob syntax.
+func ListFilesFromRootMulti(patterns ...string) ([]string, error) {
+	seen := make(map[string]struct{})
+	matches := make([]string, 0)
+	for _, pattern := range patterns {
+		found, err := ListFilesFromRoot(pattern)
+		if err != nil {
+			return nil, ers.Wrap(err)
+		}
+		for _, match := range found {
+			if _, ok := seen[match]; !ok {
+				seen[match] = struct{}{}
+				matches = append(matches, match)
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
 // WalkFromRoot walks the file tree rooted at root, calling fn for each file or
 // directory in the tree, including root.
+// Ignored directories (see SetIgnoredDirs) are skipped without calling fn.
 func WalkFromRoot(fn fs.WalkDirFunc) error {
 	root := GetRoot()
 	if root == "" {
-		return ers.New("root not set")
+		return ers.Wrap(ErrRootNotSet)
 	}
 
-	err := filepath.WalkDir(root, fn)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root && isIgnoredDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		return fn(path, d, err)
+	})
 	if err != nil {
 		return ers.Wrap(err)
 	}
@@ -384,7 +1549,7 @@ func WalkFromRoot(fn fs.WalkDirFunc) error {
 func GetRootInfo() (os.FileInfo, error) {
 	root := GetRoot()
 	if root == "" {
-		return nil, ers.New("root not set")
+		return nil, ers.Wrap(ErrRootNotSet)
 	}
 
 	fi, err := os.Stat(root)
@@ -405,11 +1570,52 @@ func GetRootName() string {
 	return fi.Name()
 }
 
+// RootInfo bundles facts about the project root.
+type RootInfo struct {
+	// Path is the absolute, cleaned root path
+	Path string
+	// Name is the base name of the root directory
+	Name string
+	// Parent is the parent directory of root, empty at the filesystem root
+	Parent string
+	// Info describes the root directory
+	Info os.FileInfo
+	// Source tells how the root was established
+	Source RootSource
+}
+
+// Root returns the path, name, parent, file info and source of the root.
+// Returns error if root is not set or cannot be accessed.
+func Root() (RootInfo, error) {
+	path, err := GetRootAbs()
+	if err != nil {
+		return RootInfo{}, ers.Wrap(err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return RootInfo{}, ers.Wrap(err)
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		parent = ""
+	}
+
+	return RootInfo{
+		Path:   path,
+		Name:   fi.Name(),
+		Parent: parent,
+		Info:   fi,
+		Source: GetRootSource(),
+	}, nil
+}
+
 // ValidateRoot verifies that root is properly set and exists on the filesystem
 func ValidateRoot() error {
 	root := GetRoot()
 	if root == "" {
-		return ers.New("root not set")
+		return ers.Wrap(ErrRootNotSet)
 	}
 
 	fi, err := os.Stat(root)
@@ -424,28 +1630,151 @@ func ValidateRoot() error {
 	return nil
 }
 
-// ClearRoot unsets the GROOT environment variable
-func ClearRoot() {
-	os.Unsetenv(grootEnv)
+// ClearRoot removes the root from the root store.
+// Returns ErrRootLocked if the root is locked.
+func ClearRoot() error {
+	return setRootPath("", "")
 }
 
 // IsInRoot checks if the given path is within the project root directory
 func IsInRoot(path string) bool {
-	root := GetRoot()
-	if root == "" {
+	cleanRoot, err := canonRoot()
+	if err != nil {
 		return false
 	}
 
-	cleanPath := ensureCleanPath(path)
-	cleanRoot := ensureCleanPath(root)
+	rel, err := relFromCanonRoot(cleanRoot, path)
+	if err != nil {
+		return false
+	}
 
-	rel, err := filepath.Rel(cleanRoot, cleanPath)
+	// Names merely starting with ".." (e.g. "..cache") are still in root
+	return !escapesBase(rel)
+}
+
+// IsDirectChildOfRoot checks if the given path is an immediate child of the
+// project root directory. Root itself and deeper descendants are not.
+func IsDirectChildOfRoot(path string) bool {
+	cleanRoot, err := canonRoot()
 	if err != nil {
 		return false
 	}
 
-	// Check if path attempts to traverse outside root with ../
-	return !strings.HasPrefix(rel, "..")
+	cleanPath, err := CanonPath(path)
+	if err != nil || cleanPath == cleanRoot {
+		return false
+	}
+
+	return filepath.Dir(cleanPath) == cleanRoot
+}
+
+// DepthInRoot returns the nesting level of path under root: 0 for root
+// itself, 1 for its direct children, 2 for their children and so on.
+// Returns an error if root is not set or if path is not under root.
+func DepthInRoot(path string) (int, error) {
+	rel, err := GetRelativeToRoot(path)
+	if err != nil {
+		return 0, ers.Wrap(err)
+	}
+	if escapesBase(rel) {
+		return 0, ers.New("path %s is outside root", path)
+	}
+	if rel == "." {
+		return 0, nil
+	}
+	return strings.Count(rel, string(os.PathSeparator)) + 1, nil
+}
+
+// Absolute root cache, valid while the raw root equals rootAbsRaw.
+var (
+	rootAbsMu  sync.Mutex
+	rootAbsRaw string
+	rootAbs    string
+)
+
+// GetRootAbs returns the root as an absolute, cleaned path.
+// The result is cached until the root changes.
+// Returns error if root is not set.
+func GetRootAbs() (string, error) {
+	root := GetRoot()
+	if root == "" {
+		return "", ers.Wrap(ErrRootNotSet)
+	}
+
+	rootAbsMu.Lock()
+	defer rootAbsMu.Unlock()
+	if root == rootAbsRaw {
+		return rootAbs, nil
+	}
+
+	abs, err := absCleanPath(root)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	rootAbsRaw, rootAbs = root, abs
+	return abs, nil
+}
+
+// PathOptions controls the normalizations applied by CanonPath.
+type PathOptions struct {
+	// EvalSymlinks resolves symbolic links of existing paths
+	EvalSymlinks bool
+	// FoldCase lowercases paths, for case-insensitive filesystems
+	FoldCase bool
+}
+
+// Current path comparison options. Symlinks are resolved by default on macOS,
+// where temp paths under /var are reported as /private/var once resolved.
+var pathOptions = PathOptions{EvalSymlinks: runtime.GOOS == "darwin"}
+
+// SetPathOptions changes the normalizations applied by CanonPath and the
+// path comparison functions.
+func SetPathOptions(opts PathOptions) {
+	pathOptions = opts
+}
+
+// CanonPath returns the canonical form of path used for comparisons by IsRoot,
+// IsInRoot and GetRelativeToRoot.
+//
+// The path is always trimmed, cleaned and made absolute. If EvalSymlinks is
+// set (the default on macOS), symbolic links are resolved; for a path that does
+// not exist, its nearest existing ancestor is resolved instead. If FoldCase is
+// set, the result is lowercased.
+func CanonPath(path string) (string, error) {
+	canon, err := absCleanPath(path)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	if pathOptions.EvalSymlinks {
+		canon = evalSymlinksPartial(canon)
+	}
+	if pathOptions.FoldCase {
+		canon = strings.ToLower(canon)
+	}
+	return canon, nil
+}
+
+// canonRoot returns the canonical form of the root.
+func canonRoot() (string, error) {
+	root, err := GetRootAbs()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return CanonPath(root)
+}
+
+// RootID returns a stable, anonymous project identifier: the first 16 hex
+// characters of the SHA-256 of the canonical root path (see CanonPath). It is
+// the same across runs for the same root and does not reveal the path, e.g.
+// for per-project telemetry.
+// Returns ErrRootNotSet if root is not set.
+func RootID() (string, error) {
+	cleanRoot, err := canonRoot()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	sum := sha256.Sum256([]byte(cleanRoot))
+	return hex.EncodeToString(sum[:8]), nil
 }
 
 // MustGetRoot returns the root directory of the project.
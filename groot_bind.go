@@ -0,0 +1,142 @@
+package groot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovila98/ers"
+)
+
+// durationType is used to parse time.Duration fields with time.ParseDuration
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// BindEnv populates the struct pointed to by dest from the process environment,
+// typically after SetRoot has loaded the env files.
+//
+// Fields are bound using struct tags:
+//
+// - env:"KEY" names the variable to read (untagged fields are skipped, except
+// nested structs which are bound recursively)
+//
+// - default:"value" is used when the variable is unset
+//
+// - required:"true" reports an error when the variable is unset and has no default
+//
+// Supported field types are strings, booleans, integers, floats,
+// time.Duration and slices of those (comma separated).
+// Returns an error aggregating every missing or unparseable field.
+func BindEnv(dest any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ers.New("dest must be a non-nil pointer to a struct")
+	}
+
+	var errs []error
+	bindStruct(v.Elem(), &errs)
+	if len(errs) > 0 {
+		return ers.Wrap(errors.Join(errs...))
+	}
+	return nil
+}
+
+// UnmarshalEnv returns a T populated from the process environment as BindEnv does.
+func UnmarshalEnv[T any]() (T, error) {
+	var dest T
+	err := BindEnv(&dest)
+	return dest, ers.Wrap(err)
+}
+
+// bindStruct binds each exported field of v, appending failures to errs
+func bindStruct(v reflect.Value, errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key, tagged := field.Tag.Lookup("env")
+		if !tagged {
+			if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+				bindStruct(v.Field(i), errs)
+			}
+			continue
+		}
+
+		value, exists := os.LookupEnv(key)
+		if !exists {
+			value, exists = field.Tag.Lookup("default")
+		}
+		if !exists {
+			if field.Tag.Get("required") == "true" {
+				*errs = append(*errs, fmt.Errorf("%s: required variable %s is not set", field.Name, key))
+			}
+			continue
+		}
+
+		if err := setField(v.Field(i), value); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: cannot parse %s=%q: %w", field.Name, key, value, err))
+		}
+	}
+}
+
+// setField parses value into the field according to its type
+func setField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(value, ",")
+		if strings.TrimSpace(value) == "" {
+			parts = nil
+		}
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setField(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package groot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindDatabase struct {
+	URL      string `env:"GROOT_TEST_DB_URL" required:"true"`
+	MaxConns uint8  `env:"GROOT_TEST_DB_MAX_CONNS" default:"4"`
+}
+
+type bindConfig struct {
+	Name     string        `env:"GROOT_TEST_NAME" default:"app"`
+	Port     int           `env:"GROOT_TEST_PORT" default:"8080"`
+	Debug    bool          `env:"GROOT_TEST_DEBUG"`
+	Ratio    float64       `env:"GROOT_TEST_RATIO"`
+	Timeout  time.Duration `env:"GROOT_TEST_TIMEOUT" default:"5s"`
+	Hosts    []string      `env:"GROOT_TEST_HOSTS"`
+	Ports    []int         `env:"GROOT_TEST_PORTS"`
+	Database bindDatabase
+	Untagged string
+	internal string `env:"GROOT_TEST_NAME"`
+}
+
+// bindKeys are the variables read when binding bindConfig
+var bindKeys = []string{
+	"GROOT_TEST_NAME", "GROOT_TEST_PORT", "GROOT_TEST_DEBUG", "GROOT_TEST_RATIO",
+	"GROOT_TEST_TIMEOUT", "GROOT_TEST_HOSTS", "GROOT_TEST_PORTS",
+	"GROOT_TEST_DB_URL", "GROOT_TEST_DB_MAX_CONNS",
+}
+
+// setBindEnv unsets every bind key, then sets env
+func setBindEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range bindKeys {
+		unsetEnv(t, key)
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestBindEnv(t *testing.T) {
+	setBindEnv(t, map[string]string{
+		"GROOT_TEST_PORT":    "9090",
+		"GROOT_TEST_DEBUG":   "true",
+		"GROOT_TEST_RATIO":   "0.5",
+		"GROOT_TEST_TIMEOUT": "1m30s",
+		"GROOT_TEST_HOSTS":   "a.example, b.example",
+		"GROOT_TEST_PORTS":   "80,443",
+		"GROOT_TEST_DB_URL":  "postgres://db",
+	})
+
+	var cfg bindConfig
+	if err := BindEnv(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := bindConfig{
+		Name:     "app",
+		Port:     9090,
+		Debug:    true,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Hosts:    []string{"a.example", "b.example"},
+		Ports:    []int{80, 443},
+		Database: bindDatabase{URL: "postgres://db", MaxConns: 4},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("BindEnv() = %+v, want %+v", cfg, want)
+	}
+
+	got, err := UnmarshalEnv[bindConfig]()
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalEnv() = %+v, %v, want %+v, nil", got, err, want)
+	}
+}
+
+func TestBindEnvEmptyValues(t *testing.T) {
+	// A set but empty variable overrides the default
+	setBindEnv(t, map[string]string{
+		"GROOT_TEST_NAME":   "",
+		"GROOT_TEST_HOSTS":  "",
+		"GROOT_TEST_DB_URL": "postgres://db",
+	})
+	var cfg bindConfig
+	if err := BindEnv(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "" || len(cfg.Hosts) != 0 {
+		t.Errorf("Name = %q, Hosts = %q, want both empty", cfg.Name, cfg.Hosts)
+	}
+}
+
+func TestBindEnvErrors(t *testing.T) {
+	setBindEnv(t, map[string]string{
+		"GROOT_TEST_PORT":         "http",
+		"GROOT_TEST_DEBUG":        "maybe",
+		"GROOT_TEST_TIMEOUT":      "5",
+		"GROOT_TEST_PORTS":        "80,x",
+		"GROOT_TEST_DB_MAX_CONNS": "300",
+	})
+
+	var cfg bindConfig
+	err := BindEnv(&cfg)
+	if err == nil {
+		t.Fatal("BindEnv() = nil, want errors")
+	}
+	// Every failing field is reported at once
+	for _, want := range []string{
+		"Port: cannot parse GROOT_TEST_PORT",
+		"Debug: cannot parse GROOT_TEST_DEBUG",
+		"Timeout: cannot parse GROOT_TEST_TIMEOUT",
+		"Ports: cannot parse GROOT_TEST_PORTS",
+		"MaxConns: cannot parse GROOT_TEST_DB_MAX_CONNS",
+		"URL: required variable GROOT_TEST_DB_URL is not set",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("BindEnv() error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestBindEnvUnsupportedType(t *testing.T) {
+	t.Setenv("GROOT_TEST_MAP", "a=1")
+	var cfg struct {
+		Values map[string]string `env:"GROOT_TEST_MAP"`
+	}
+	if err := BindEnv(&cfg); err == nil || !strings.Contains(err.Error(), "unsupported type") {
+		t.Errorf("BindEnv() = %v, want unsupported type error", err)
+	}
+}
+
+func TestBindEnvInvalidDest(t *testing.T) {
+	var cfg bindConfig
+	var nilCfg *bindConfig
+	name := "x"
+	for _, dest := range []any{nil, cfg, nilCfg, &name} {
+		if err := BindEnv(dest); err == nil {
+			t.Errorf("BindEnv(%T) = nil, want error", dest)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package groot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ovila98/ers"
+)
+
+// SetRootFromConfigFile searches upward for filename (e.g. "app.toml") and
+// sets the root to its directory.
+//
+// When rootKey is not empty and the file defines it as a top-level string,
+// its value is used as the root instead, relative to the file's directory
+// unless absolute. The format is chosen by extension: .json, .toml, .yaml and
+// .yml. Only top-level scalar keys are read, nested tables and mappings are
+// ignored.
+//
+// Falls back to the working directory if the project dir cannot be determined.
+// Returns ErrNoRootFound if the file is not found, and an error if it cannot
+// be parsed or the declared root is not a directory.
+func SetRootFromConfigFile(filename string, rootKey string) error {
+	filename = strings.TrimSpace(filename)
+	if filename == "" {
+		return ers.New("filename cannot be empty")
+	}
+	startDir, err := getSearchDir()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	dir := ""
+	for _, path := range IterateThroughPath(startDir) {
+		if f, err := os.Stat(filepath.Join(path, filename)); err == nil && !f.IsDir() {
+			dir = path
+			break
+		}
+	}
+	if dir == "" {
+		return ers.Wrapf(ErrNoRootFound, "config file %s", filename)
+	}
+
+	root := dir
+	if rootKey = strings.TrimSpace(rootKey); rootKey != "" {
+		configPath := filepath.Join(dir, filename)
+		value, found, err := readConfigKey(configPath, rootKey)
+		if err != nil {
+			return ers.Wrapf(err, "cannot parse %s", configPath)
+		}
+		if found && value != "" {
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(dir, value)
+			}
+			root = filepath.Clean(value)
+		}
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if !info.IsDir() {
+		return ers.Wrapf(ErrNotDirectory, "%s", root)
+	}
+	return setRootPath(root, SourceMarker)
+}
+
+// readConfigKey returns the top-level string value of key in the config file
+// at path, as a format chosen by its extension
+func readConfigKey(path, key string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, ers.Wrap(err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return jsonConfigKey(data, key)
+	case ".toml":
+		return tomlConfigKey(data, key)
+	case ".yaml", ".yml":
+		return yamlConfigKey(data, key)
+	default:
+		return "", false, ers.New("unsupported config format %q", filepath.Ext(path))
+	}
+}
+
+// jsonConfigKey reads key from a JSON object
+func jsonConfigKey(data []byte, key string) (string, bool, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", false, ers.Wrap(err)
+	}
+	raw, ok := doc[key]
+	if !ok {
+		return "", false, nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", false, ers.Wrapf(err, "key %s is not a string", key)
+	}
+	return value, true, nil
+}
+
+// configLines splits config content into lines, dropping CRLF line endings
+func configLines(data []byte) []string {
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+}
+
+// tomlConfigKey reads key from the top level of a TOML document, i.e. before
+// the first [table] header. Values spanning several lines (arrays and
+// multi-line strings) are skipped over, or decoded when they hold key.
+func tomlConfigKey(data []byte, key string) (string, bool, error) {
+	lines := configLines(data)
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			// Keys past the first table header are not top-level
+			return "", false, nil
+		}
+
+		sep := strings.IndexByte(trimmed, '=')
+		if sep < 0 {
+			return "", false, ers.New("line %d: expected key = value", lineNo)
+		}
+		name, err := unquoteConfigValue(strings.TrimSpace(trimmed[:sep]), false)
+		if err != nil {
+			return "", false, ers.Wrapf(err, "line %d", lineNo)
+		}
+		value := strings.TrimSpace(trimmed[sep+1:])
+		var scan tomlScan
+		scan.line(value)
+		for scan.open() && i+1 < len(lines) {
+			i++
+			value += "\n" + lines[i]
+			scan.line(lines[i])
+		}
+		if scan.open() {
+			return "", false, ers.New("line %d: unterminated value", lineNo)
+		}
+		if name != key {
+			continue
+		}
+		if strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''") {
+			value, err = tomlMultilineString(value)
+		} else {
+			value, err = unquoteConfigValue(value, false)
+		}
+		if err != nil {
+			return "", false, ers.Wrapf(err, "line %d", lineNo)
+		}
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// tomlScan tracks, line after line, whether a TOML value is still open
+type tomlScan struct {
+	// depth of unclosed arrays
+	depth int
+	// delimiter of the unclosed multi-line string, if any
+	multiline string
+}
+
+// line scans one line of a value
+func (s *tomlScan) line(text string) {
+	for j := 0; j < len(text); j++ {
+		if s.multiline != "" {
+			if text[j] == '\\' && s.multiline == `"""` {
+				j++
+			} else if strings.HasPrefix(text[j:], s.multiline) {
+				j += len(s.multiline) - 1
+				s.multiline = ""
+			}
+			continue
+		}
+		switch c := text[j]; {
+		case c == '#':
+			return
+		case strings.HasPrefix(text[j:], `"""`) || strings.HasPrefix(text[j:], "'''"):
+			s.multiline = text[j : j+3]
+			j += 2
+		case c == '"' || c == '\'':
+			// Single-line strings end on the same line
+			for j++; j < len(text) && text[j] != c; j++ {
+				if c == '"' && text[j] == '\\' {
+					j++
+				}
+			}
+		case c == '[':
+			s.depth++
+		case c == ']':
+			s.depth--
+		}
+	}
+}
+
+// open reports whether the value continues on the next line
+func (s *tomlScan) open() bool {
+	return s.depth > 0 || s.multiline != ""
+}
+
+// tomlMultilineString decodes a multi-line basic or literal string, whose
+// first line break is trimmed. Basic strings support escapes and line ending backslashes.
+func tomlMultilineString(value string) (string, error) {
+	delim := value[:3]
+	body := value[3:]
+	end := -1
+	for j := 0; j < len(body); j++ {
+		if body[j] == '\\' && delim == `"""` {
+			j++
+		} else if strings.HasPrefix(body[j:], delim) {
+			end = j
+			break
+		}
+	}
+	if end < 0 {
+		return "", ers.New("unterminated string %s", delim)
+	}
+	body = strings.TrimPrefix(body[:end], "\n")
+	if delim == "'''" {
+		return body, nil
+	}
+
+	// Rewrite as a Go string literal, whose escapes are a superset of TOML's
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for j := 0; j < len(body); j++ {
+		switch c := body[j]; c {
+		case '\\':
+			if rest := strings.TrimLeft(body[j+1:], " \t"); strings.HasPrefix(rest, "\n") {
+				// A line ending backslash trims the following whitespace
+				rest = strings.TrimLeft(rest, " \t\n")
+				j = len(body) - len(rest) - 1
+				continue
+			}
+			quoted.WriteByte(c)
+			if j+1 < len(body) {
+				j++
+				quoted.WriteByte(body[j])
+			}
+		case '"':
+			quoted.WriteString(`\"`)
+		case '\n':
+			quoted.WriteString(`\n`)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+	unquoted, err := strconv.Unquote(quoted.String())
+	if err != nil {
+		return "", ers.Wrapf(err, "invalid string %s", delim)
+	}
+	return unquoted, nil
+}
+
+// yamlConfigKey reads key from the unindented lines of a YAML document.
+// Indented lines continue the previous value: they are folded into it, or
+// kept line by line for | and > block scalars, whose trailing line breaks are
+// dropped.
+func yamlConfigKey(data []byte, key string) (string, bool, error) {
+	lines := configLines(data)
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || line[0] == ' ' || line[0] == '\t' ||
+			trimmed == "---" || strings.HasPrefix(trimmed, "--- ") || trimmed == "..." {
+			continue
+		}
+
+		sep := strings.IndexByte(trimmed, ':')
+		if sep < 0 {
+			continue
+		}
+		name, err := unquoteConfigValue(strings.TrimSpace(trimmed[:sep]), true)
+		if err != nil {
+			return "", false, ers.Wrapf(err, "line %d", lineNo)
+		}
+		if name != key {
+			continue
+		}
+
+		value := strings.TrimSpace(trimmed[sep+1:])
+		var continued []string
+		for i+1 < len(lines) {
+			next := lines[i+1]
+			if strings.TrimSpace(next) != "" && next[0] != ' ' && next[0] != '\t' {
+				break
+			}
+			continued = append(continued, next)
+			i++
+		}
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			return yamlBlockScalar(continued, value[0] == '>'), true, nil
+		}
+		for _, next := range continued {
+			if next = strings.TrimSpace(next); next == "" || strings.HasPrefix(next, "#") {
+				break
+			}
+			value += " " + next
+		}
+		value, err = unquoteConfigValue(value, true)
+		if err != nil {
+			return "", false, ers.Wrapf(err, "line %d", lineNo)
+		}
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// yamlBlockScalar joins the lines of a literal or folded block scalar,
+// without their common indentation
+func yamlBlockScalar(lines []string, folded bool) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	var b strings.Builder
+	for j, line := range lines {
+		if len(line) >= indent {
+			line = line[max(indent, 0):]
+		} else {
+			line = ""
+		}
+		if j > 0 {
+			if folded && line != "" && strings.TrimSpace(lines[j-1]) != "" {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(line)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// unquoteConfigValue unquotes a double or single quoted scalar, or strips a
+// trailing comment from a bare one. In YAML, a doubled single quote escapes a
+// single quote inside single quotes.
+func unquoteConfigValue(value string, yaml bool) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := 0
+		for i := 1; i < len(value) && end == 0; i++ {
+			switch value[i] {
+			case '\\':
+				i++
+			case '"':
+				end = i
+			}
+		}
+		if end == 0 {
+			return "", ers.New("unterminated string %s", value)
+		}
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", ers.Wrapf(err, "invalid string %s", value)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			if value[i] != '\'' {
+				b.WriteByte(value[i])
+				continue
+			}
+			if yaml && i+1 < len(value) && value[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), nil
+		}
+		return "", ers.New("unterminated string %s", value)
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
@@ -0,0 +1,24 @@
+package groot
+
+import "context"
+
+// rootContextKey is the context key under which ContextWithRoot stores the root
+type rootContextKey struct{}
+
+// ContextWithRoot returns a copy of ctx carrying the current root, so handlers
+// can pass it down a call chain instead of reading the global state. ctx is
+// returned unchanged if root is not set.
+func ContextWithRoot(ctx context.Context) context.Context {
+	root := GetRoot()
+	if root == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, rootContextKey{}, root)
+}
+
+// RootFromContext returns the root stored in ctx by ContextWithRoot and
+// whether there was one.
+func RootFromContext(ctx context.Context) (string, bool) {
+	root, ok := ctx.Value(rootContextKey{}).(string)
+	return root, ok
+}
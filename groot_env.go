@@ -0,0 +1,816 @@
+package groot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/joho/godotenv"
+	"github.com/ovila98/ers"
+)
+
+// EnvOptions controls how env files are discovered and loaded by SetRoot and
+// its variants.
+type EnvOptions struct {
+	// PreserveExistingEnv never overwrites variables that were present in the
+	// process environment before loading, giving them top precedence. When
+	// false, file values replace them. In both cases, among env files the
+	// first one loaded defining a key wins. Defaults to true, as godotenv.Load.
+	// LoadEnvLayers ignores it and always overloads.
+	PreserveExistingEnv bool
+
+	// OSVariants also loads "<name>.<GOOS>" (e.g. ".env.linux") next to each
+	// env file when present. A variant takes precedence over its base file but
+	// not over files found in directories nearer to the project dir. Variants
+	// are optional and never required.
+	OSVariants bool
+
+	// UppercaseKeys uppercases every key before it is set (e.g. "port" is set
+	// as "PORT"). Values are untouched. A file defining two keys that only
+	// differ by case (e.g. "Port" and "PORT") is rejected. Off by default.
+	UppercaseKeys bool
+
+	// ExpandOSEnv expands $VAR and ${VAR} references in values against the
+	// process environment when VAR is not defined earlier in the same file.
+	// Definitions from the file take precedence over process variables.
+	// Expansion does not apply to single-quoted values.
+	ExpandOSEnv bool
+
+	// Conditions maps env filenames (base names, e.g. ".env.production") to
+	// predicates deciding whether the file is loaded. A predicate is evaluated
+	// right before its file would be loaded, so it sees variables from files
+	// loaded before it: files nearer to the project dir come first, root last.
+	// A skipped file still satisfies required env files.
+	Conditions map[string]func() bool
+
+	// Decryptor, when set, is applied to the raw content of each env file whose
+	// base name matches DecryptPattern before parsing (e.g. to plug in SOPS or
+	// age). A decryption failure aborts loading with the file path in the error.
+	Decryptor func(ciphertext []byte) ([]byte, error)
+
+	// DecryptPattern selects the files passed to Decryptor, using
+	// filepath.Match syntax. Defaults to "*.enc.env".
+	DecryptPattern string
+
+	// RequireSecureEnvPerms refuses to load env files accessible by group or
+	// others, like SSH does for private keys: permissions must be 0600 or
+	// stricter (no bit of 0077 set). The check is skipped on Windows, where
+	// Unix permission bits are not meaningful.
+	RequireSecureEnvPerms bool
+
+	// ProtectedKeys lists keys for which the process environment always wins:
+	// when already set before loading (e.g. DATABASE_URL injected by the
+	// orchestrator), env files never override them, even with
+	// PreserveExistingEnv off. Other keys follow PreserveExistingEnv, so files
+	// can provide defaults for the rest. Keys are compared after UppercaseKeys.
+	ProtectedKeys []string
+}
+
+// Default pattern of env files passed to EnvOptions.Decryptor
+const defaultDecryptPattern = "*.enc.env"
+
+// DefaultEnvOptions returns the default env loading options.
+func DefaultEnvOptions() EnvOptions {
+	return EnvOptions{PreserveExistingEnv: true}
+}
+
+// Current env loading options.
+var envOptions = DefaultEnvOptions()
+
+// Incremented by every SetEnvOptions call, see SetIdempotent
+var envOptionsGeneration atomic.Uint64
+
+// SetEnvOptions changes the options used when loading env files.
+// opts replaces every option: start from GetEnvOptions or DefaultEnvOptions
+// to keep the defaults of the options not being changed.
+func SetEnvOptions(opts EnvOptions) {
+	envOptions = opts
+	envOptionsGeneration.Add(1)
+}
+
+// GetEnvOptions returns the options used when loading env files.
+func GetEnvOptions() EnvOptions {
+	return envOptions
+}
+
+// osVariant returns the GOOS-specific variant of an env filename
+func osVariant(name string) string {
+	return name + "." + runtime.GOOS
+}
+
+// Matches $VAR and ${VAR} references in env content
+var envRefRegex = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+
+// readEnvFile reads and parses the env file at path, reporting whether its
+// content had to be normalized (see normalizeEnvData)
+func readEnvFile(path string) (map[string]string, bool, error) {
+	if err := checkEnvPerms(path); err != nil {
+		return nil, false, ers.Wrap(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, ers.Wrap(err)
+	}
+	data, err = decryptEnv(path, data)
+	if err != nil {
+		return nil, false, ers.Wrap(err)
+	}
+	data, normalized := normalizeEnvData(data)
+	parse := parseEnv
+	if isPropertiesFile(path) {
+		parse = parseProperties
+	}
+	envMap, err := parse(data)
+	if err != nil {
+		return nil, false, ers.Wrapf(err, "parsing %s", path)
+	}
+	return envMap, normalized, nil
+}
+
+// UTF-8 byte order mark some Windows editors prepend to files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// normalizeEnvData strips a leading UTF-8 BOM and converts CRLF line endings
+// to LF, which would otherwise end up in the first key or in values.
+// Reports whether data was changed.
+func normalizeEnvData(data []byte) ([]byte, bool) {
+	normalized := false
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+		normalized = true
+	}
+	if bytes.Contains(data, []byte("\r\n")) {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		normalized = true
+	}
+	return data, normalized
+}
+
+// checkEnvPerms enforces RequireSecureEnvPerms for the file at path
+func checkEnvPerms(path string) error {
+	if !envOptions.RequireSecureEnvPerms || runtime.GOOS == "windows" {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if perm := fi.Mode().Perm(); perm&0o077 != 0 {
+		return ers.New("env file %s has insecure permissions %04o, expected 0600 or stricter", path, perm)
+	}
+	return nil
+}
+
+// decryptEnv applies the configured Decryptor to data if path matches the
+// decrypt pattern
+func decryptEnv(path string, data []byte) ([]byte, error) {
+	if envOptions.Decryptor == nil {
+		return data, nil
+	}
+	pattern := envOptions.DecryptPattern
+	if pattern == "" {
+		pattern = defaultDecryptPattern
+	}
+	matched, err := filepath.Match(pattern, filepath.Base(path))
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	if !matched {
+		return data, nil
+	}
+	plaintext, err := envOptions.Decryptor(data)
+	if err != nil {
+		return nil, ers.Wrapf(err, "decrypting %s", path)
+	}
+	return plaintext, nil
+}
+
+// parseEnv parses env content, expanding references to process variables
+// when ExpandOSEnv is set.
+func parseEnv(data []byte) (map[string]string, error) {
+	if !envOptions.ExpandOSEnv {
+		envMap, err := godotenv.UnmarshalBytes(data)
+		return envMap, ers.Wrap(err)
+	}
+
+	fileMap, err := godotenv.UnmarshalBytes(data)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	// Referenced process variables are declared ahead of the content with
+	// placeholder values, so godotenv expands them like earlier definitions.
+	// The real values are substituted afterwards so that they are never
+	// parsed themselves.
+	var prefix bytes.Buffer
+	declared := make(map[string]struct{})
+	values := make(map[string]string)
+	for _, match := range envRefRegex.FindAllSubmatch(data, -1) {
+		name := string(match[1])
+		value, exists := os.LookupEnv(name)
+		if _, seen := declared[name]; seen || !exists {
+			continue
+		}
+		declared[name] = struct{}{}
+		placeholder := fmt.Sprintf("__groot_os_env_%d__", len(values))
+		values[placeholder] = value
+		fmt.Fprintf(&prefix, "%s=%s\n", name, placeholder)
+	}
+	if len(values) == 0 {
+		return fileMap, nil
+	}
+
+	envMap, err := godotenv.UnmarshalBytes(append(prefix.Bytes(), data...))
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	for key, value := range envMap {
+		if _, inFile := fileMap[key]; !inFile {
+			delete(envMap, key)
+			continue
+		}
+		for placeholder, osValue := range values {
+			value = strings.ReplaceAll(value, placeholder, osValue)
+		}
+		envMap[key] = value
+	}
+	return envMap, nil
+}
+
+// shouldLoadEnvFile evaluates the condition registered for the file at path
+func shouldLoadEnvFile(path string) bool {
+	cond, ok := envOptions.Conditions[filepath.Base(path)]
+	return !ok || cond == nil || cond()
+}
+
+// transformEnv applies the key transformations enabled in envOptions
+func transformEnv(envMap map[string]string) (map[string]string, error) {
+	if !envOptions.UppercaseKeys {
+		return envMap, nil
+	}
+	upper := make(map[string]string, len(envMap))
+	origins := make(map[string]string, len(envMap))
+	for key, value := range envMap {
+		upperKey := strings.ToUpper(key)
+		if origin, exists := origins[upperKey]; exists {
+			return nil, ers.New("env keys %q and %q collide once uppercased", origin, key)
+		}
+		origins[upperKey] = key
+		upper[upperKey] = value
+	}
+	return upper, nil
+}
+
+// Keys set in the environment by groot's env loading, guarded by loadedKeysMu
+var (
+	loadedKeysMu sync.Mutex
+	loadedKeys   = make(map[string]struct{})
+)
+
+// Callback observing each variable set by env loading, see OnEnvSet
+var envSetHook func(key, value, sourceFile string)
+
+// OnEnvSet registers fn to be called for each variable env loading actually
+// sets into the process environment, with the env file providing it, e.g. to
+// audit the configuration at startup. Variables are reported in load order:
+// file by file, sorted by key within a file. Variables kept because they were
+// already set are not reported. sourceFile is empty for LoadEnvFromReader and
+// LoadEnvFromStdin.
+// fn replaces any previous callback; pass nil to remove it.
+func OnEnvSet(fn func(key, value, sourceFile string)) {
+	envSetHook = fn
+}
+
+// isProtectedKey reports whether key is listed in EnvOptions.ProtectedKeys
+func isProtectedKey(key string) bool {
+	for _, protected := range envOptions.ProtectedKeys {
+		if protected == key {
+			return true
+		}
+	}
+	return false
+}
+
+// envLoad applies env maps for one load operation, remembering the keys it
+// set so that earlier maps take precedence over later ones
+type envLoad struct {
+	set map[string]struct{}
+	// overload makes later maps replace values set by earlier ones and
+	// variables present beforehand regardless of PreserveExistingEnv, as
+	// godotenv.Overload. ProtectedKeys are still kept.
+	overload bool
+	// kept collects the keys not set because present beforehand
+	kept []string
+}
+
+// newEnvLoad starts a load operation
+func newEnvLoad() *envLoad {
+	return &envLoad{set: make(map[string]struct{})}
+}
+
+// apply sets the variables of envMap, read from sourceFile, in key order,
+// unless set earlier by this load (without overload) or, with
+// PreserveExistingEnv (without overload) or for ProtectedKeys, present in the
+// environment beforehand
+func (l *envLoad) apply(envMap map[string]string, sourceFile string) error {
+	envMap, err := transformEnv(envMap)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	keys := make([]string, 0, len(envMap))
+	for key := range envMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := envMap[key]
+		if _, done := l.set[key]; done {
+			if !l.overload {
+				continue
+			}
+		} else if _, exists := os.LookupEnv(key); exists &&
+			((envOptions.PreserveExistingEnv && !l.overload) || isProtectedKey(key)) {
+			l.kept = append(l.kept, key)
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return ers.Wrap(err)
+		}
+		l.set[key] = struct{}{}
+		loadedKeysMu.Lock()
+		loadedKeys[key] = struct{}{}
+		loadedKeysMu.Unlock()
+		if envSetHook != nil {
+			envSetHook(key, value, sourceFile)
+		}
+	}
+	return nil
+}
+
+// loadEnvFiles loads paths in order and records them in result.
+// Earlier files take precedence over later ones.
+func loadEnvFiles(result *SetRootResult, paths []string) error {
+	load := newEnvLoad()
+	for _, path := range paths {
+		if !shouldLoadEnvFile(path) {
+			result.SkippedEnvFiles = append(result.SkippedEnvFiles, path)
+			continue
+		}
+		envMap, normalized, err := readEnvFile(path)
+		if err != nil {
+			return ers.Wrap(err)
+		}
+		if normalized {
+			result.NormalizedEnvFiles = append(result.NormalizedEnvFiles, path)
+		}
+		if len(envMap) == 0 {
+			result.EmptyEnvFiles = append(result.EmptyEnvFiles, path)
+		}
+		if err := load.apply(envMap, path); err != nil {
+			return ers.Wrapf(err, "loading %s", path)
+		}
+		result.EnvFiles = append(result.EnvFiles, path)
+	}
+	return nil
+}
+
+// LoadEnvFromReader parses env content from r and sets its variables.
+// Variables already present in the environment are kept if PreserveExistingEnv is set.
+func LoadEnvFromReader(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	data, _ = normalizeEnvData(data)
+	envMap, err := parseEnv(data)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	return ers.Wrap(newEnvLoad().apply(envMap, ""))
+}
+
+// LoadEnvFromStdin parses env content piped through stdin and sets its variables.
+// Returns ErrNoEnvDefined if stdin is a terminal or provides no data.
+func LoadEnvFromStdin() error {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if fi.Mode()&os.ModeCharDevice != 0 {
+		return ers.Wrap(ErrNoEnvDefined)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ers.Wrap(ErrNoEnvDefined)
+	}
+	return ers.Wrap(LoadEnvFromReader(bytes.NewReader(data)))
+}
+
+// ValidateEnvSchema checks that the given env files only define keys in allowed.
+// Relative file paths are resolved from root with FromRoot. Files are read as
+// env loading reads them (see EnvOptions).
+// Returns an error listing each file path with its unexpected keys.
+// Nothing is loaded into the environment.
+func ValidateEnvSchema(allowed []string, envFiles ...string) error {
+	allowedKeys := make(map[string]struct{}, len(allowed))
+	for _, key := range allowed {
+		allowedKeys[strings.TrimSpace(key)] = struct{}{}
+	}
+
+	var problems []string
+	for _, envFile := range envFiles {
+		path := FromRoot(envFile)
+		envMap, _, err := readEnvFile(path)
+		if err != nil {
+			return ers.Wrapf(err, "reading %s", path)
+		}
+		var unknown []string
+		for key := range envMap {
+			if _, ok := allowedKeys[key]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			problems = append(problems, fmt.Sprintf("%s: %s", path, strings.Join(unknown, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return ers.New("unexpected env keys: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// EnvValue is a resolved env value along with the file that provided it.
+type EnvValue struct {
+	Value  string
+	Source string
+}
+
+// ResolveEnvWithSources reads the given env files in order and returns, for
+// each key, the winning value and the file that provided it.
+// Precedence matches loading: the first file defining a key wins.
+// Relative file paths are resolved from root with FromRoot. Files are read as
+// env loading reads them (see EnvOptions).
+// The process environment is not modified.
+func ResolveEnvWithSources(envFiles ...string) (map[string]EnvValue, error) {
+	resolved := make(map[string]EnvValue)
+	for _, envFile := range envFiles {
+		path := FromRoot(envFile)
+		envMap, _, err := readEnvFile(path)
+		if err != nil {
+			return nil, ers.Wrapf(err, "reading %s", path)
+		}
+		for key, value := range envMap {
+			if _, exists := resolved[key]; !exists {
+				resolved[key] = EnvValue{Value: value, Source: path}
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// EnvFilesByDir returns, for each directory searched upward from the project
+// directory, the env files matching envFiles found there. The search stops at
+// root when the project dir is under it, and at the filesystem root otherwise.
+// Directories without matches are omitted. Nothing is loaded.
+func EnvFilesByDir(envFiles ...string) (map[string][]string, error) {
+	envNames := cleanFilenames(envFiles...)
+	if len(envNames) == 0 {
+		return nil, ers.Wrap(ErrBadEnvsDefined)
+	}
+	sort.Strings(envNames)
+
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	root := GetRoot()
+	if root != "" && !isWithin(root, projectDir) {
+		root = ""
+	}
+
+	byDir := make(map[string][]string)
+	for _, path := range IterateThroughPath(projectDir) {
+		found, err := findFiles(path, envNames)
+		if err != nil {
+			return nil, ers.Wrap(err)
+		}
+		if len(found) > 0 {
+			byDir[path] = found
+		}
+		if root != "" && path == filepath.Clean(root) {
+			break
+		}
+	}
+	return byDir, nil
+}
+
+// LoadEnvWithDefaults loads the given env files, then sets each key of defaults
+// that is still not present in the environment. Precedence is: process or file
+// value if present, else default.
+// Relative file paths are resolved from root with FromRoot.
+// Returns the sorted keys that fell back to their default.
+func LoadEnvWithDefaults(defaults map[string]string, envFiles ...string) ([]string, error) {
+	paths := make([]string, 0, len(envFiles))
+	for _, envFile := range envFiles {
+		paths = append(paths, FromRoot(envFile))
+	}
+	var result SetRootResult
+	if err := loadEnvFiles(&result, paths); err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	fallbacks := make([]string, 0)
+	for key, value := range defaults {
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return nil, ers.Wrap(err)
+		}
+		fallbacks = append(fallbacks, key)
+	}
+	sort.Strings(fallbacks)
+	return fallbacks, nil
+}
+
+// WithEnvFiles runs fn in a hermetic environment holding only the variables of
+// the given env files (and the root env var, so groot keeps working), then
+// restores the original environment, even if fn panics.
+// Relative file paths are resolved from root with FromRoot.
+//
+// The process environment is global: other goroutines see the sandbox while
+// fn runs, so WithEnvFiles is not safe for concurrent use.
+func WithEnvFiles(fn func() error, envFiles ...string) error {
+	paths := make([]string, 0, len(envFiles))
+	for _, envFile := range envFiles {
+		paths = append(paths, FromRoot(envFile))
+	}
+
+	snapshot := EnvSnapshot()
+	defer RestoreEnv(snapshot)
+	rootValue, rootSet := os.LookupEnv(grootEnv)
+	os.Clearenv()
+	if rootSet {
+		if err := os.Setenv(grootEnv, rootValue); err != nil {
+			return ers.Wrap(err)
+		}
+	}
+
+	var result SetRootResult
+	if err := loadEnvFiles(&result, paths); err != nil {
+		return ers.Wrap(err)
+	}
+	return ers.Wrap(fn())
+}
+
+// envKeyRegex matches the variable names a POSIX shell can export
+var envKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExportScript returns "export KEY='value'" lines, one per key, that a POSIX
+// shell can source to reproduce the given variables, e.g. for subprocesses.
+// Without keys, every variable loaded from env files and still set is
+// exported. Values are single-quoted so quotes, newlines and $ survive as is.
+// Returns error if a key is not a valid shell variable name or is not set.
+func ExportScript(keys ...string) (string, error) {
+	explicit := len(keys) > 0
+	if !explicit {
+		loadedKeysMu.Lock()
+		for key := range loadedKeys {
+			keys = append(keys, key)
+		}
+		loadedKeysMu.Unlock()
+		sort.Strings(keys)
+	}
+
+	var script strings.Builder
+	var missing []string
+	for _, key := range keys {
+		if !envKeyRegex.MatchString(key) {
+			return "", ers.New("%q is not a valid shell variable name", key)
+		}
+		value, exists := os.LookupEnv(key)
+		if !exists {
+			if explicit {
+				missing = append(missing, key)
+			}
+			continue
+		}
+		fmt.Fprintf(&script, "export %s=%s\n", key, shellQuote(value))
+	}
+	if len(missing) > 0 {
+		return "", ers.New("variables not set: %s", strings.Join(missing, ", "))
+	}
+	return script.String(), nil
+}
+
+// shellQuote single-quotes value for a POSIX shell, closing the quotes around
+// each embedded single quote
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// ErrEmptyEnvKeys indicates required env variables are unset or blank
+var ErrEmptyEnvKeys = errors.New("missing or empty env keys")
+
+// RequireNonEmptyEnv checks that each key is set in the environment to a value
+// that is not empty or whitespace only, catching "KEY=" misconfigurations.
+// Returns ErrEmptyEnvKeys listing every offending key at once.
+func RequireNonEmptyEnv(keys ...string) error {
+	var problems []string
+	for _, key := range keys {
+		value, exists := os.LookupEnv(key)
+		switch {
+		case !exists:
+			problems = append(problems, key+" (unset)")
+		case strings.TrimSpace(value) == "":
+			problems = append(problems, key+" (empty)")
+		}
+	}
+	if len(problems) > 0 {
+		return ers.Wrapf(ErrEmptyEnvKeys, "%s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// EnvSnapshot returns a copy of the whole process environment, to be restored
+// with RestoreEnv, e.g. around SetRoot in tests.
+func EnvSnapshot() map[string]string {
+	env := os.Environ()
+	snapshot := make(map[string]string, len(env))
+	for _, kv := range env {
+		// Skip the first byte: Windows has entries such as "=C:=C:\dir"
+		if i := strings.IndexByte(kv[min(1, len(kv)):], '=') + 1; i > 0 {
+			snapshot[kv[:i]] = kv[i+1:]
+		}
+	}
+	return snapshot
+}
+
+// RestoreEnv makes the process environment match snapshot: variables absent
+// from it are unset, e.g. those added by SetRoot, and the others take their
+// snapshot value. Like every environment change, it affects all goroutines.
+// The cached root survives the root env var being unset; call ClearRoot to
+// forget it too.
+func RestoreEnv(snapshot map[string]string) error {
+	for key := range EnvSnapshot() {
+		if _, keep := snapshot[key]; !keep {
+			if err := os.Unsetenv(key); err != nil {
+				return ers.Wrap(err)
+			}
+		}
+	}
+	for key, value := range snapshot {
+		if current, exists := os.LookupEnv(key); exists && current == value {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return ers.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// PreviewEnvImpact reads the env file and reports, without applying anything,
+// the sorted keys loading it would add to the process environment and those
+// whose current value it would change. Loading rules are honored: with
+// PreserveExistingEnv (the default) or for ProtectedKeys, existing variables
+// are kept and never reported as changed.
+// A relative file path is resolved from root with FromRoot.
+func PreviewEnvImpact(file string) (newKeys, changedKeys []string, err error) {
+	path := FromRoot(file)
+	envMap, _, err := readEnvFile(path)
+	if err != nil {
+		return nil, nil, ers.Wrapf(err, "reading %s", path)
+	}
+	envMap, err = transformEnv(envMap)
+	if err != nil {
+		return nil, nil, ers.Wrapf(err, "reading %s", path)
+	}
+
+	newKeys, changedKeys = make([]string, 0), make([]string, 0)
+	for key, value := range envMap {
+		current, exists := os.LookupEnv(key)
+		switch {
+		case !exists:
+			newKeys = append(newKeys, key)
+		case current != value && !envOptions.PreserveExistingEnv && !isProtectedKey(key):
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	sort.Strings(newKeys)
+	sort.Strings(changedKeys)
+	return newKeys, changedKeys, nil
+}
+
+// EnvLayer is a named env file of a layered configuration, see LoadEnvLayers.
+type EnvLayer struct {
+	// Name identifies the layer in statuses and errors (e.g. "base", "local")
+	Name string
+	// Filename is the env file base name (e.g. ".env.local")
+	Filename string
+	// Optional layers may be missing
+	Optional bool
+}
+
+// EnvLayerStatus reports how a layer was handled by LoadEnvLayers.
+type EnvLayerStatus struct {
+	Name string
+	// Path is the env file found for the layer, empty if none was found
+	Path string
+	// Loaded reports whether the file was loaded. A found file is not loaded
+	// when its condition (see EnvOptions.Conditions) is false.
+	Loaded bool
+	// KeptKeys lists the keys of the loaded file not applied because they are
+	// ProtectedKeys already present in the environment, sorted
+	KeptKeys []string
+}
+
+// LoadEnvLayers loads named env layers in slice order, later layers overriding
+// the values of earlier ones, e.g. base, then environment, then local.
+//
+// Each layer's file is the nearest one found searching upward from the
+// project directory, stopping at root when the project dir is under it.
+// Layers overload the process environment as godotenv.Overload does: their
+// values replace variables present before the call whatever
+// PreserveExistingEnv is, except ProtectedKeys, which are kept and reported in
+// KeptKeys.
+//
+// Returns the status of every layer handled, and ErrMissingEnvs naming the
+// first required layer not found, in which case later layers are not loaded.
+func LoadEnvLayers(layers []EnvLayer) ([]EnvLayerStatus, error) {
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	root := GetRoot()
+	if root != "" && !isWithin(root, projectDir) {
+		root = ""
+	}
+	dirs := make([]string, 0)
+	for _, path := range IterateThroughPath(projectDir) {
+		dirs = append(dirs, path)
+		if root != "" && path == filepath.Clean(root) {
+			break
+		}
+	}
+
+	load := newEnvLoad()
+	load.overload = true
+	statuses := make([]EnvLayerStatus, 0, len(layers))
+	for _, layer := range layers {
+		names := cleanFilenames(layer.Filename)
+		if len(names) == 0 {
+			return statuses, ers.Wrapf(ErrBadEnvsDefined, "layer %s: %q", layer.Name, layer.Filename)
+		}
+		status := EnvLayerStatus{Name: layer.Name}
+		for _, dir := range dirs {
+			found, err := findFiles(dir, names)
+			if err != nil {
+				return statuses, ers.Wrapf(err, "layer %s", layer.Name)
+			}
+			if len(found) > 0 {
+				status.Path = found[0]
+				break
+			}
+		}
+
+		if status.Path == "" {
+			if !layer.Optional {
+				return statuses, ers.Wrapf(ErrMissingEnvs, "layer %s: %s", layer.Name, names[0])
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+		if shouldLoadEnvFile(status.Path) {
+			envMap, _, err := readEnvFile(status.Path)
+			if err != nil {
+				return statuses, ers.Wrapf(err, "layer %s", layer.Name)
+			}
+			load.kept = nil
+			if err := load.apply(envMap, status.Path); err != nil {
+				return statuses, ers.Wrapf(err, "layer %s: loading %s", layer.Name, status.Path)
+			}
+			status.Loaded = true
+			status.KeptKeys = load.kept
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
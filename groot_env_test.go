@@ -0,0 +1,319 @@
+package groot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// unsetEnv unsets key for the duration of the test
+func unsetEnv(t testing.TB, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestSetRootEnvPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		preserve bool
+		want     string
+	}{
+		{"shell wins by default", true, "shell"},
+		{"file wins without PreserveExistingEnv", false, "file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGroot(t)
+			root := tempDir(t)
+			writeFile(t, filepath.Join(root, ".env"), "GROOT_TEST_KEY=file\nGROOT_TEST_OTHER=file\n")
+			useProjectDir(t, root)
+			t.Setenv("GROOT_TEST_KEY", "shell")
+			unsetEnv(t, "GROOT_TEST_OTHER")
+
+			opts := DefaultEnvOptions()
+			opts.PreserveExistingEnv = tt.preserve
+			SetEnvOptions(opts)
+			if err := SetRoot(".env"); err != nil {
+				t.Fatal(err)
+			}
+			if got := GetRoot(); got != root {
+				t.Fatalf("GetRoot() = %q, want %q", got, root)
+			}
+			if got := os.Getenv("GROOT_TEST_KEY"); got != tt.want {
+				t.Errorf("GROOT_TEST_KEY = %q, want %q", got, tt.want)
+			}
+			if got := os.Getenv("GROOT_TEST_OTHER"); got != "file" {
+				t.Errorf("GROOT_TEST_OTHER = %q, want %q", got, "file")
+			}
+		})
+	}
+}
+
+func TestNormalizeEnvData(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		want           string
+		wantNormalized bool
+	}{
+		{"unchanged", "A=1\nB=2\n", "A=1\nB=2\n", false},
+		{"BOM", "\xEF\xBB\xBFA=1\n", "A=1\n", true},
+		{"CRLF", "A=1\r\nB=2\r\n", "A=1\nB=2\n", true},
+		{"BOM and CRLF", "\xEF\xBB\xBFA=1\r\nB=2\r\n", "A=1\nB=2\n", true},
+		{"lone CR kept", "A=1\rB\n", "A=1\rB\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, normalized := normalizeEnvData([]byte(tt.data))
+			if string(got) != tt.want || normalized != tt.wantNormalized {
+				t.Errorf("normalizeEnvData(%q) = %q, %v, want %q, %v",
+					tt.data, got, normalized, tt.want, tt.wantNormalized)
+			}
+		})
+	}
+}
+
+func TestSetRootNormalizesWindowsEnvFile(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	envPath := filepath.Join(root, ".env")
+	writeFile(t, envPath, "\xEF\xBB\xBFGROOT_TEST_A=1\r\nGROOT_TEST_B=two\r\n")
+	useProjectDir(t, root)
+	unsetEnv(t, "GROOT_TEST_A")
+	unsetEnv(t, "GROOT_TEST_B")
+
+	result, err := SetRootWithResult(".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("GROOT_TEST_A"); got != "1" {
+		t.Errorf("GROOT_TEST_A = %q, want %q", got, "1")
+	}
+	if got := os.Getenv("GROOT_TEST_B"); got != "two" {
+		t.Errorf("GROOT_TEST_B = %q, want %q", got, "two")
+	}
+	if len(result.NormalizedEnvFiles) != 1 || result.NormalizedEnvFiles[0] != envPath {
+		t.Errorf("NormalizedEnvFiles = %q, want [%q]", result.NormalizedEnvFiles, envPath)
+	}
+}
+
+func TestResolveEnvWithSourcesNormalizes(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	writeFile(t, filepath.Join(root, ".env"), "\xEF\xBB\xBFA=1\r\nB=two\r\n")
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := ResolveEnvWithSources(".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 2 || resolved["A"].Value != "1" || resolved["B"].Value != "two" {
+		t.Errorf("ResolveEnvWithSources() = %v, want A=1 and B=two", resolved)
+	}
+	if err := ValidateEnvSchema([]string{"A", "B"}, ".env"); err != nil {
+		t.Errorf("ValidateEnvSchema() = %v, want nil", err)
+	}
+}
+
+func TestLoadEnvLayersOverloads(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	projectDir := filepath.Join(root, "cmd", "app")
+	writeFile(t, filepath.Join(root, ".env"), "GROOT_TEST_A=base\nGROOT_TEST_B=base\nGROOT_TEST_P=base\n")
+	writeFile(t, filepath.Join(projectDir, ".env.local"), "GROOT_TEST_B=local\n")
+	useProjectDir(t, projectDir)
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GROOT_TEST_A", "shell")
+	t.Setenv("GROOT_TEST_P", "shell")
+	unsetEnv(t, "GROOT_TEST_B")
+	opts := DefaultEnvOptions()
+	opts.ProtectedKeys = []string{"GROOT_TEST_P"}
+	SetEnvOptions(opts)
+
+	statuses, err := LoadEnvLayers([]EnvLayer{
+		{Name: "base", Filename: ".env"},
+		{Name: "local", Filename: ".env.local"},
+		{Name: "missing", Filename: ".env.missing", Optional: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EnvLayerStatus{
+		{Name: "base", Path: filepath.Join(root, ".env"), Loaded: true, KeptKeys: []string{"GROOT_TEST_P"}},
+		{Name: "local", Path: filepath.Join(projectDir, ".env.local"), Loaded: true},
+		{Name: "missing"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("LoadEnvLayers() = %+v, want %+v", statuses, want)
+	}
+	for key, value := range map[string]string{
+		"GROOT_TEST_A": "base",
+		"GROOT_TEST_B": "local",
+		"GROOT_TEST_P": "shell",
+	} {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestSetRootLoadsSymlinkedEnvFileOnce(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	projectDir := filepath.Join(root, "cmd")
+	envPath := filepath.Join(root, ".env")
+	writeFile(t, filepath.Join(root, "app.id"), "")
+	writeFile(t, envPath, "GROOT_TEST_A=1\n")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The project dir links the root .env, and config links root itself, so
+	// .env is reached through cmd/.env, config/.env and .env
+	symlink(t, filepath.Join("..", ".env"), filepath.Join(projectDir, ".env"))
+	symlink(t, ".", filepath.Join(root, "config"))
+	useProjectDir(t, projectDir)
+	unsetEnv(t, "GROOT_TEST_A")
+
+	result, err := setRoot([]string{"app.id"}, []string{".env"}, []string{"config/.env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(projectDir, ".env")}
+	if !reflect.DeepEqual(result.EnvFiles, want) {
+		t.Errorf("EnvFiles = %q, want %q", result.EnvFiles, want)
+	}
+	if got := os.Getenv("GROOT_TEST_A"); got != "1" {
+		t.Errorf("GROOT_TEST_A = %q, want %q", got, "1")
+	}
+}
+
+func TestParseEnvExpandOSEnv(t *testing.T) {
+	resetGroot(t)
+	t.Setenv("GROOT_TEST_HOME", "/home/gopher")
+	t.Setenv("GROOT_TEST_NAME", "os")
+	unsetEnv(t, "GROOT_TEST_UNSET")
+	opts := DefaultEnvOptions()
+	opts.ExpandOSEnv = true
+	SetEnvOptions(opts)
+
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{"OS-only variable", "DIR=${GROOT_TEST_HOME}/app\nBARE=$GROOT_TEST_HOME\n",
+			map[string]string{"DIR": "/home/gopher/app", "BARE": "/home/gopher"}},
+		{"file over OS", "GROOT_TEST_NAME=file\nGREETING=hello ${GROOT_TEST_NAME}\n",
+			map[string]string{"GROOT_TEST_NAME": "file", "GREETING": "hello file"}},
+		{"single quotes not expanded", "RAW='${GROOT_TEST_HOME}'\n",
+			map[string]string{"RAW": "${GROOT_TEST_HOME}"}},
+		{"double quotes expanded", "QUOTED=\"${GROOT_TEST_HOME}\"\n",
+			map[string]string{"QUOTED": "/home/gopher"}},
+		{"unset variable", "EMPTY=${GROOT_TEST_UNSET}\n",
+			map[string]string{"EMPTY": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnv([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEnv(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvWithoutExpandOSEnv(t *testing.T) {
+	resetGroot(t)
+	t.Setenv("GROOT_TEST_HOME", "/home/gopher")
+	got, err := parseEnv([]byte("DIR=${GROOT_TEST_HOME}/app\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["DIR"] != "/app" {
+		t.Errorf("DIR = %q, want %q", got["DIR"], "/app")
+	}
+}
+
+func TestSetRootReportsEmptyEnvFiles(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	writeFile(t, filepath.Join(root, "app.id"), "")
+	writeFile(t, filepath.Join(root, ".env"), "GROOT_TEST_A=1\n")
+	writeFile(t, filepath.Join(root, "empty.env"), "")
+	writeFile(t, filepath.Join(root, "comments.env"), "# nothing here\n\n   # still nothing\n")
+	useProjectDir(t, root)
+	unsetEnv(t, "GROOT_TEST_A")
+
+	result, err := SetRootWithResult("app.id", ".env", "empty.env", "comments.env")
+	if err != nil {
+		t.Fatalf("SetRootWithResult() = %v, want empty files to satisfy required env files", err)
+	}
+	wantEmpty := []string{filepath.Join(root, "empty.env"), filepath.Join(root, "comments.env")}
+	if !reflect.DeepEqual(result.EmptyEnvFiles, wantEmpty) {
+		t.Errorf("EmptyEnvFiles = %q, want %q", result.EmptyEnvFiles, wantEmpty)
+	}
+	if len(result.EnvFiles) != 3 {
+		t.Errorf("EnvFiles = %q, want all 3 files loaded", result.EnvFiles)
+	}
+	if got := os.Getenv("GROOT_TEST_A"); got != "1" {
+		t.Errorf("GROOT_TEST_A = %q, want %q", got, "1")
+	}
+}
+
+func TestSetRootEnvFilesBetweenProjectDirAndRoot(t *testing.T) {
+	outer := tempDir(t)
+	root := filepath.Join(outer, "root")
+	writeFile(t, filepath.Join(outer, ".env"), "GROOT_TEST_A=outer\n")
+	writeFile(t, filepath.Join(root, "app.id"), "")
+	writeFile(t, filepath.Join(root, ".env"), "GROOT_TEST_A=root\n")
+	writeFile(t, filepath.Join(root, "a", ".env"), "GROOT_TEST_A=a\n")
+	writeFile(t, filepath.Join(root, "a", "b", ".env"), "GROOT_TEST_A=b\n")
+
+	tests := []struct {
+		name       string
+		projectDir string
+		want       []string
+	}{
+		{"project dir is root", root, []string{filepath.Join(root, ".env")}},
+		{"project dir below root", filepath.Join(root, "a", "b"), []string{
+			filepath.Join(root, "a", "b", ".env"),
+			filepath.Join(root, "a", ".env"),
+			filepath.Join(root, ".env"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGroot(t)
+			useProjectDir(t, tt.projectDir)
+			unsetEnv(t, "GROOT_TEST_A")
+
+			result, err := SetRootWithResult("app.id", ".env")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Root != root {
+				t.Errorf("Root = %q, want %q", result.Root, root)
+			}
+			if !reflect.DeepEqual(result.EnvFiles, tt.want) {
+				t.Errorf("EnvFiles = %q, want %q", result.EnvFiles, tt.want)
+			}
+			wantExcluded := []string{filepath.Join(outer, ".env")}
+			if !reflect.DeepEqual(result.ExcludedEnvFiles, wantExcluded) {
+				t.Errorf("ExcludedEnvFiles = %q, want %q", result.ExcludedEnvFiles, wantExcluded)
+			}
+			// The env file nearest to the project dir wins
+			if got, want := os.Getenv("GROOT_TEST_A"), filepath.Base(tt.projectDir); got != want {
+				t.Errorf("GROOT_TEST_A = %q, want %q", got, want)
+			}
+		})
+	}
+}
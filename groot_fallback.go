@@ -0,0 +1,142 @@
+package groot
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ovila98/ers"
+)
+
+// RootStrategy returns a candidate root, or an empty root to defer to the next
+// strategy of the fallback chain.
+type RootStrategy func() (string, error)
+
+// Fallback chain state, guarded by fallbackMu. A failed resolution is cached
+// in fallbackErr for fallbackGeneration, the root generation it was attempted
+// at. fallbackRunning is set while the strategies run, unlocked.
+var (
+	fallbackMu         sync.Mutex
+	fallbackChain      []RootStrategy
+	fallbackErr        error
+	fallbackFailed     bool
+	fallbackGeneration uint64
+	fallbackRunning    bool
+)
+
+// SetFallbackChain sets the strategies used to resolve the root when it is not
+// set, e.g. SetFallbackChain(EnvVarStrategy("APP_ROOT"), GitStrategy, GoModStrategy).
+//
+// The chain is evaluated lazily, by the first GetRoot or ResolveRoot call that
+// finds no root: strategies are tried in order until one returns a non-empty
+// root, which is then set with source SourceFallback. The outcome, success or
+// failure, is cached until ClearRoot (or any other root change). Strategies
+// run without any lock held; while they run, GetRoot and ResolveRoot calls,
+// including those made by the strategies themselves, see no root.
+//
+// Call with no strategies to disable the chain.
+func SetFallbackChain(strategies ...RootStrategy) {
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+	fallbackChain = append([]RootStrategy(nil), strategies...)
+	fallbackErr, fallbackFailed = nil, false
+}
+
+// ResolveRoot returns the root, resolving it with the fallback chain if it is
+// not set (see SetFallbackChain).
+// Returns ErrRootNotSet if there is no root and no chain, or while the chain
+// runs, and ErrNoRootFound joined with the strategy errors if no strategy
+// provided a root.
+func ResolveRoot() (string, error) {
+	if root := loadRoot(); root != "" {
+		return root, nil
+	}
+
+	fallbackMu.Lock()
+	if len(fallbackChain) == 0 {
+		fallbackMu.Unlock()
+		return "", ers.Wrap(ErrRootNotSet)
+	}
+	if fallbackRunning {
+		fallbackMu.Unlock()
+		return "", ers.Wrapf(ErrRootNotSet, "fallback chain running")
+	}
+	rootMu.RLock()
+	generation := rootGeneration
+	rootMu.RUnlock()
+	if fallbackFailed && generation == fallbackGeneration {
+		err := fallbackErr
+		fallbackMu.Unlock()
+		return "", err
+	}
+	chain := fallbackChain
+	fallbackRunning = true
+	fallbackMu.Unlock()
+	defer func() {
+		fallbackMu.Lock()
+		fallbackRunning = false
+		fallbackMu.Unlock()
+	}()
+
+	errs := []error{ErrNoRootFound}
+	for _, strategy := range chain {
+		root, err := strategy()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if root = strings.TrimSpace(root); root == "" {
+			continue
+		}
+		if err := setRootPath(root, SourceFallback); err != nil {
+			return "", ers.Wrap(err)
+		}
+		return root, nil
+	}
+
+	err := ers.Wrap(errors.Join(errs...))
+	fallbackMu.Lock()
+	fallbackErr = err
+	fallbackFailed, fallbackGeneration = true, generation
+	fallbackMu.Unlock()
+	return "", err
+}
+
+// GitStrategy resolves the root as SetRootFromGit does.
+func GitStrategy() (string, error) {
+	startDir, err := getSearchDir()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return FindGitRootFrom(startDir), nil
+}
+
+// GoModStrategy resolves the root as SetRootFromGoMod does.
+func GoModStrategy() (string, error) {
+	startDir, err := getSearchDir()
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return FindGoModRootFrom(startDir), nil
+}
+
+// EnvVarStrategy returns a strategy reading the root from the key env var.
+// An unset or empty variable defers to the next strategy, a value that is not
+// a directory is an error.
+func EnvVarStrategy(key string) RootStrategy {
+	return func() (string, error) {
+		root := strings.TrimSpace(os.Getenv(key))
+		if root == "" {
+			return "", nil
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return "", ers.Wrapf(err, "env var %s", key)
+		}
+		if !info.IsDir() {
+			return "", ers.Wrapf(ErrNotDirectory, "env var %s: %s", key, root)
+		}
+		return root, nil
+	}
+}
@@ -0,0 +1,116 @@
+package groot
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingStrategy returns a strategy returning root and err, counting calls
+func countingStrategy(calls *int, root string, err error) RootStrategy {
+	return func() (string, error) {
+		*calls++
+		return root, err
+	}
+}
+
+func TestFallbackChainOrder(t *testing.T) {
+	resetGroot(t)
+	first, second := tempDir(t), tempDir(t)
+	errStrategy := errors.New("strategy failed")
+	var failing, empty, winner, after int
+	SetFallbackChain(
+		countingStrategy(&failing, "", errStrategy),
+		countingStrategy(&empty, "  ", nil),
+		countingStrategy(&winner, first, nil),
+		countingStrategy(&after, second, nil),
+	)
+
+	// GetRoot resolves and caches the root as a side effect
+	if got := GetRoot(); got != first {
+		t.Fatalf("GetRoot() = %q, want %q", got, first)
+	}
+	if got := GetRootSource(); got != SourceFallback {
+		t.Errorf("GetRootSource() = %q, want %q", got, SourceFallback)
+	}
+	if root, err := ResolveRoot(); err != nil || root != first {
+		t.Errorf("ResolveRoot() = %q, %v, want %q, nil", root, err, first)
+	}
+	if failing != 1 || empty != 1 || winner != 1 || after != 0 {
+		t.Errorf("strategy calls = %d, %d, %d, %d, want 1, 1, 1, 0", failing, empty, winner, after)
+	}
+}
+
+func TestFallbackChainFailureCache(t *testing.T) {
+	resetGroot(t)
+	errStrategy := errors.New("strategy failed")
+	var calls int
+	SetFallbackChain(countingStrategy(&calls, "", errStrategy))
+
+	for i := 0; i < 2; i++ {
+		_, err := ResolveRoot()
+		if !errors.Is(err, ErrNoRootFound) || !errors.Is(err, errStrategy) {
+			t.Fatalf("ResolveRoot() = %v, want ErrNoRootFound joined with the strategy error", err)
+		}
+	}
+	if GetRoot() != "" || calls != 1 {
+		t.Fatalf("strategy called %d times, want the failure cached after 1", calls)
+	}
+
+	// A root change invalidates the cached failure
+	if err := ClearRoot(); err != nil {
+		t.Fatal(err)
+	}
+	ResolveRoot()
+	if calls != 2 {
+		t.Errorf("strategy called %d times after ClearRoot, want 2", calls)
+	}
+	// So does a new chain
+	root := tempDir(t)
+	SetFallbackChain(countingStrategy(&calls, root, nil))
+	if got, err := ResolveRoot(); err != nil || got != root {
+		t.Errorf("ResolveRoot() with a new chain = %q, %v, want %q, nil", got, err, root)
+	}
+
+	// Disabling the chain reports an unset root once cleared
+	SetFallbackChain()
+	if err := ClearRoot(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveRoot(); !errors.Is(err, ErrRootNotSet) {
+		t.Errorf("ResolveRoot() without chain = %v, want ErrRootNotSet", err)
+	}
+}
+
+func TestFallbackStrategyCallingGetRoot(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	var seen string
+	SetFallbackChain(func() (string, error) {
+		seen = GetRoot()
+		return root, nil
+	})
+
+	done := make(chan string, 1)
+	go func() { done <- GetRoot() }()
+	select {
+	case got := <-done:
+		if got != root || seen != "" {
+			t.Errorf("GetRoot() = %q with %q seen by the strategy, want %q and empty", got, seen, root)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("strategy calling GetRoot deadlocked")
+	}
+}
+
+func TestEnvVarStrategy(t *testing.T) {
+	dir := tempDir(t)
+	for value, wantErr := range map[string]bool{"": false, dir: false, filepath.Join(dir, "missing"): true} {
+		t.Setenv("GROOT_TEST_ROOT", value)
+		root, err := EnvVarStrategy("GROOT_TEST_ROOT")()
+		if (err != nil) != wantErr || (err == nil && root != value) {
+			t.Errorf("EnvVarStrategy() with %q = %q, %v", value, root, err)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+package groot
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ovila98/ers"
+)
+
+// Default directory names skipped by the walking helpers
+var defaultIgnoredDirs = []string{".git", "node_modules", "vendor", ".idea"}
+
+// Directory names skipped by the walking helpers
+var ignoredDirs = makeIgnoredDirs(defaultIgnoredDirs)
+
+// makeIgnoredDirs builds the ignored directory set from base names
+func makeIgnoredDirs(dirs []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(dirs))
+	for _, dir := range dirs {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			set[dir] = struct{}{}
+		}
+	}
+	return set
+}
+
+// SetIgnoredDirs replaces the directory names skipped by WalkFromRoot and every
+// helper built on it (ListFilesFromRootRecursive, RelativeFilesFromRoot, ...).
+// Names are matched against directory base names anywhere under root.
+// The defaults are .git, node_modules, vendor and .idea; call SetIgnoredDirs()
+// with no names to clear them.
+func SetIgnoredDirs(dirs ...string) {
+	ignoredDirs = makeIgnoredDirs(dirs)
+}
+
+// GetIgnoredDirs returns the sorted directory names skipped by the walking helpers.
+func GetIgnoredDirs() []string {
+	dirs := make([]string, 0, len(ignoredDirs))
+	for dir := range ignoredDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// isIgnoredDir reports whether a directory with the given base name is skipped
+func isIgnoredDir(name string) bool {
+	_, ok := ignoredDirs[name]
+	return ok
+}
+
+// ListFilesFromRootRecursive returns the sorted paths of the files anywhere
+// under root whose base name matches pattern.
+// Pattern follows filepath.Match syntax. Ignored directories are skipped.
+func ListFilesFromRootRecursive(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	files := make([]string, 0)
+	err := WalkFromRoot(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ok, _ := filepath.Match(pattern, d.Name()); ok {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// GroupedGlobFromRoot matches pattern against file names anywhere under root
+// as ListFilesFromRootRecursive does, grouping the sorted matching names by
+// their forward-slashed directory relative to root ("." for root itself),
+// e.g. for per-package processing.
+// Returns error if root is not set or pattern is malformed.
+func GroupedGlobFromRoot(pattern string) (map[string][]string, error) {
+	root := GetRoot()
+	if root == "" {
+		return nil, ers.Wrap(ErrRootNotSet)
+	}
+	files, err := ListFilesFromRootRecursive(pattern)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	groups := make(map[string][]string)
+	for _, file := range files {
+		rel, err := filepath.Rel(root, filepath.Dir(file))
+		if err != nil {
+			return nil, ers.Wrap(err)
+		}
+		dir := filepath.ToSlash(rel)
+		groups[dir] = append(groups[dir], filepath.Base(file))
+	}
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+	return groups, nil
+}
+
+// RelativeFilesFromRoot returns the sorted, forward-slashed paths relative to
+// root of every file under root with one of the given extensions (e.g. ".go"
+// or "go"), or of every file if none is given.
+// Ignored directories (e.g. .git, node_modules) are skipped.
+// Returns error if root is not set.
+func RelativeFilesFromRoot(extensions ...string) ([]string, error) {
+	root := GetRoot()
+	if root == "" {
+		return nil, ers.Wrap(ErrRootNotSet)
+	}
+
+	wanted := newExtSet(extensions)
+	files := make([]string, 0)
+	err := WalkFromRoot(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !wanted.match(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// extSet is a set of file extensions, matching any file when empty
+type extSet map[string]struct{}
+
+// newExtSet normalizes extensions given with or without their dot
+func newExtSet(exts []string) extSet {
+	set := make(extSet, len(exts))
+	for _, ext := range exts {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = struct{}{}
+	}
+	return set
+}
+
+// match reports whether path has one of the extensions
+func (e extSet) match(path string) bool {
+	if len(e) == 0 {
+		return true
+	}
+	_, ok := e[filepath.Ext(path)]
+	return ok
+}
+
+// RelFromRootBatch returns a map from each of paths to its root-relative,
+// forward-slashed form, canonicalizing the root only once for the batch.
+// Paths outside root are omitted from the map and returned in outside, in
+// input order.
+// Returns error if root is not set or a path cannot be canonicalized.
+func RelFromRootBatch(paths []string) (rel map[string]string, outside []string, err error) {
+	cleanRoot, err := canonRoot()
+	if err != nil {
+		return nil, nil, ers.Wrap(err)
+	}
+
+	rel = make(map[string]string, len(paths))
+	for _, path := range paths {
+		cleanPath, err := CanonPath(path)
+		if err != nil {
+			return nil, nil, ers.Wrap(err)
+		}
+		r, err := filepath.Rel(cleanRoot, cleanPath)
+		if err != nil || escapesBase(r) {
+			outside = append(outside, path)
+			continue
+		}
+		rel[path] = filepath.ToSlash(r)
+	}
+	return rel, outside, nil
+}
+
+// Default base name patterns recognized as env files by FindAllEnvFiles
+var defaultEnvFilePatterns = []string{"*.env", ".env*"}
+
+// Base name patterns recognized as env files by FindAllEnvFiles
+var envFilePatterns = defaultEnvFilePatterns
+
+// SetEnvFilePatterns replaces the base name patterns (filepath.Match syntax)
+// recognized by FindAllEnvFiles. Defaults to "*.env" and ".env*"; call with no
+// patterns to restore the defaults.
+// Returns error if a pattern is malformed.
+func SetEnvFilePatterns(patterns ...string) error {
+	if len(patterns) == 0 {
+		envFilePatterns = defaultEnvFilePatterns
+		return nil
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return ers.Wrapf(err, "pattern %q", pattern)
+		}
+	}
+	envFilePatterns = append([]string(nil), patterns...)
+	return nil
+}
+
+// FindAllEnvFiles returns the sorted, forward-slashed paths relative to root of
+// every env file anywhere under root (see SetEnvFilePatterns), e.g. to audit
+// stray secret files. Ignored directories are skipped and nothing is loaded.
+// Returns error if root is not set.
+func FindAllEnvFiles() ([]string, error) {
+	root := GetRoot()
+	if root == "" {
+		return nil, ers.Wrap(ErrRootNotSet)
+	}
+
+	files := make([]string, 0)
+	err := WalkFromRoot(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		for _, pattern := range envFilePatterns {
+			if ok, _ := filepath.Match(pattern, d.Name()); ok {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, filepath.ToSlash(rel))
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// ErrMissingFiles indicates one or more required project files were not found
+var ErrMissingFiles = errors.New("missing project files")
+
+// RequireFiles checks that every root-relative path exists, e.g. at startup to
+// assert "schema.sql", "migrations/" and "templates/" are present. A path
+// ending in a separator must be a directory, any other may be a file or a
+// directory.
+// Returns ErrRootNotSet if root is not set and ErrMissingFiles listing every
+// missing path at once.
+func RequireFiles(relPaths ...string) error {
+	root := GetRoot()
+	if root == "" {
+		return ers.Wrap(ErrRootNotSet)
+	}
+
+	var missing []string
+	for _, relPath := range relPaths {
+		wantDir := strings.HasSuffix(relPath, "/") || strings.HasSuffix(relPath, `\`)
+		info, err := os.Stat(filepath.Join(root, ensureCleanPath(relPath)))
+		switch {
+		case err != nil:
+			missing = append(missing, relPath)
+		case wantDir && !info.IsDir():
+			missing = append(missing, relPath+" (not a directory)")
+		}
+	}
+	if len(missing) > 0 {
+		return ers.Wrapf(ErrMissingFiles, "%s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ListFilesOnlyFromRoot behaves like ListFilesFromRoot but drops matches that
+// are directories. Each match costs an extra os.Stat; symbolic links are
+// followed and matches that vanish meanwhile are dropped.
+func ListFilesOnlyFromRoot(pattern string) ([]string, error) {
+	matches, err := listFromRootByKind(pattern, false)
+	return matches, ers.Wrap(err)
+}
+
+// ListDirsOnlyFromRoot behaves like ListFilesFromRoot but keeps only matches
+// that are directories, at the same extra os.Stat cost as ListFilesOnlyFromRoot.
+func ListDirsOnlyFromRoot(pattern string) ([]string, error) {
+	matches, err := listFromRootByKind(pattern, true)
+	return matches, ers.Wrap(err)
+}
+
+// listFromRootByKind keeps the matches of pattern that are directories if
+// dirs is set, and the other ones otherwise
+func listFromRootByKind(pattern string, dirs bool) ([]string, error) {
+	matches, err := ListFilesFromRoot(pattern)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	kept := make([]string, 0, len(matches))
+	for _, match := range matches {
+		fi, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		if fi.IsDir() == dirs {
+			kept = append(kept, match)
+		}
+	}
+	return kept, nil
+}
+
+// AssertRootContains checks that every name exists directly under root, as a
+// post-condition after any SetRootFrom* call guarding against picking the
+// wrong directory. Like RequireFiles, a name ending in a separator must be a
+// directory.
+// Returns ErrRootNotSet if root is not set, an error if a name is a nested
+// path and ErrMissingFiles listing every missing name at once.
+func AssertRootContains(names ...string) error {
+	for _, name := range names {
+		base := strings.TrimRight(name, `/\`)
+		if base == "" || strings.ContainsAny(base, `/\`) || base == "." || base == ".." {
+			return ers.New("%q is not a direct child name", name)
+		}
+	}
+	return ers.Wrap(RequireFiles(names...))
+}
+
+// ErrReadOnlyRoot indicates a write was refused because read-only mode is on
+var ErrReadOnlyRoot = errors.New("root is read-only")
+
+// Whether write helpers are disabled, see SetReadOnly
+var readOnlyRoot atomic.Bool
+
+// SetReadOnly enables or disables read-only mode, for tools that must never
+// modify the project tree. While enabled, helpers that would create or write
+// files return ErrReadOnlyRoot without touching the filesystem; read and
+// discovery functions are unaffected.
+//
+// Helpers honoring the flag: SetRootFromPathCreate (when the directory does
+// not exist yet) and IsRootWritableFS.
+func SetReadOnly(enabled bool) {
+	readOnlyRoot.Store(enabled)
+}
+
+// IsReadOnly reports whether read-only mode is enabled (see SetReadOnly).
+func IsReadOnly() bool {
+	return readOnlyRoot.Load()
+}
+
+// Number of leading bytes of each file searched by SearchFromRoot
+const maxSearchFileSize = 1 << 20
+
+// SearchFromRoot returns the sorted, forward-slashed paths relative to root of
+// the files with one of the given extensions (or any file if none is given)
+// containing substring, as a lightweight project-wide grep.
+// Only the first MiB of each file is searched, files containing a NUL byte
+// there are considered binary and skipped, as are unreadable files and
+// ignored directories.
+// Returns error if root is not set or substring is empty.
+func SearchFromRoot(substring string, extensions ...string) ([]string, error) {
+	root := GetRoot()
+	if root == "" {
+		return nil, ers.Wrap(ErrRootNotSet)
+	}
+	if substring == "" {
+		return nil, ers.New("substring cannot be empty")
+	}
+
+	needle := []byte(substring)
+	wanted := newExtSet(extensions)
+	files := make([]string, 0)
+	err := WalkFromRoot(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !wanted.match(path) {
+			return nil
+		}
+		data, err := readFileHead(path, maxSearchFileSize)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 || !bytes.Contains(data, needle) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// readFileHead reads at most limit bytes from the start of the file at path
+func readFileHead(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit))
+	return data, ers.Wrap(err)
+}
+
+// IsRootWritableFS reports whether the filesystem holding root accepts writes,
+// e.g. to fall back to a temp directory when root is on a read-only image
+// layer. It probes by creating then removing a hidden temporary file directly
+// in root: false means the filesystem is mounted read-only (EROFS).
+//
+// Other failures, such as a permission denied on root itself or a full disk,
+// say nothing about the mount and are returned as errors, so a writable
+// filesystem may still be unusable and a false negative is possible. On Plan 9,
+// which has no EROFS, a read-only mount is returned as an error too.
+// Returns ErrRootNotSet if root is not set and ErrReadOnlyRoot in read-only
+// mode (see SetReadOnly), as the probe writes to root.
+func IsRootWritableFS() (bool, error) {
+	root := GetRoot()
+	if root == "" {
+		return false, ers.Wrap(ErrRootNotSet)
+	}
+	if IsReadOnly() {
+		return false, ers.Wrapf(ErrReadOnlyRoot, "cannot probe %s", root)
+	}
+
+	probe, err := os.CreateTemp(root, ".groot-probe-*")
+	if isReadOnlyFSError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ers.Wrap(err)
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return true, ers.Wrapf(err, "removing probe file")
+	}
+	return true, nil
+}
@@ -0,0 +1,31 @@
+package groot
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestIsRootWritableFS(t *testing.T) {
+	resetGroot(t)
+	if _, err := IsRootWritableFS(); !errors.Is(err, ErrRootNotSet) {
+		t.Errorf("IsRootWritableFS() without root = %v, want ErrRootNotSet", err)
+	}
+	root := tempDir(t)
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	writable, err := IsRootWritableFS()
+	if err != nil || !writable {
+		t.Fatalf("IsRootWritableFS() = %v, %v, want true, nil", writable, err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Errorf("probe file left in root: %v", entries)
+	}
+
+	SetReadOnly(true)
+	t.Cleanup(func() { SetReadOnly(false) })
+	if _, err := IsRootWritableFS(); !errors.Is(err, ErrReadOnlyRoot) {
+		t.Errorf("IsRootWritableFS() in read-only mode = %v, want ErrReadOnlyRoot", err)
+	}
+}
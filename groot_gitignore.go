@@ -0,0 +1,208 @@
+package groot
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ovila98/ers"
+)
+
+// gitignoreRule is a single pattern of a .gitignore file
+type gitignoreRule struct {
+	// base is the root-relative, slashed directory of the .gitignore file
+	base string
+	// re matches the path relative to base (anchored) or the base name
+	re       *regexp.Regexp
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// match reports whether the rule matches the root-relative, slashed path
+func (r gitignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	if r.anchored {
+		return r.re.MatchString(rel)
+	}
+	return r.re.MatchString(rel[strings.LastIndex(rel, "/")+1:])
+}
+
+// parseGitignore reads the rules of the .gitignore file at path, if any.
+// base is the root-relative, slashed directory containing it.
+func parseGitignore(path, base string) ([]gitignoreRule, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	defer f.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A slash anywhere but at the end anchors the pattern to base
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		re, err := regexp.Compile("^" + gitignorePatternToRegex(line) + "$")
+		if err != nil {
+			return nil, ers.Wrapf(err, "invalid pattern %q in %s", line, path)
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ers.Wrap(err)
+	}
+	return rules, nil
+}
+
+// gitignorePatternToRegex converts a gitignore glob to a regular expression
+func gitignorePatternToRegex(pattern string) string {
+	var re strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "/**":
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
+
+// WalkFromRootGitAware walks the file tree rooted at root like WalkFromRoot,
+// skipping entries ignored by the .gitignore files found at root and in
+// nested directories. Ignored directories are not descended into.
+//
+// Supported: comments, blank lines, negation (!), directory-only patterns
+// (trailing /), anchored patterns (leading or inner /), *, ?, [...] classes,
+// ** and backslash escapes. Rules of deeper .gitignore files and later lines
+// take precedence.
+//
+// Not supported: .git/info/exclude, the global core.excludesFile, escaped
+// trailing spaces and files ignored through the git index.
+func WalkFromRootGitAware(fn fs.WalkDirFunc) error {
+	root := GetRoot()
+	if root == "" {
+		return ers.Wrap(ErrRootNotSet)
+	}
+
+	rulesByDir := make(map[string][]gitignoreRule)
+	err := WalkFromRoot(func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fn(path, d, err)
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != "." {
+			if isGitIgnored(rulesByDir, rel, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		} else {
+			rel = ""
+		}
+
+		if d.IsDir() {
+			rules, err := parseGitignore(filepath.Join(path, ".gitignore"), rel)
+			if err != nil {
+				return err
+			}
+			rulesByDir[rel] = rules
+		}
+		return fn(path, d, nil)
+	})
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	return nil
+}
+
+// isGitIgnored evaluates the rules of every ancestor directory of rel, from
+// root down, the last matching rule deciding
+func isGitIgnored(rulesByDir map[string][]gitignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	dir := ""
+	parts := strings.Split(rel, "/")
+	for i := 0; i < len(parts); i++ {
+		for _, rule := range rulesByDir[dir] {
+			if rule.match(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+		if dir == "" {
+			dir = parts[i]
+		} else {
+			dir += "/" + parts[i]
+		}
+	}
+	return ignored
+}
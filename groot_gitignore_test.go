@@ -0,0 +1,144 @@
+package groot
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWalkFromRootGitAware(t *testing.T) {
+	tests := []struct {
+		name string
+		// files maps slashed root-relative paths to their content
+		files map[string]string
+		// want lists the files walked, without the .gitignore files
+		want []string
+	}{
+		{
+			name: "negation",
+			files: map[string]string{
+				".gitignore": "# logs\n*.log\n!keep.log\n",
+				"a.log":      "", "keep.log": "", "sub/b.log": "", "b.txt": "",
+			},
+			want: []string{"b.txt", "keep.log"},
+		},
+		{
+			name: "nested re-include",
+			files: map[string]string{
+				".gitignore":     "*.tmp\n",
+				"sub/.gitignore": "!important.tmp\n",
+				"x.tmp":          "", "sub/important.tmp": "", "sub/other.tmp": "", "other/important.tmp": "",
+			},
+			want: []string{"sub/important.tmp"},
+		},
+		{
+			name: "directory only",
+			files: map[string]string{
+				".gitignore":    "build/\n",
+				"build/out.bin": "", "cmd/build/out.bin": "", "other/build": "",
+			},
+			want: []string{"other/build"},
+		},
+		{
+			name: "anchored",
+			files: map[string]string{
+				".gitignore": "/top.txt\n",
+				"top.txt":    "", "sub/top.txt": "",
+			},
+			want: []string{"sub/top.txt"},
+		},
+		{
+			name: "anchored in nested gitignore",
+			files: map[string]string{
+				"sub/.gitignore": "/top.txt\n",
+				"top.txt":        "", "sub/top.txt": "", "sub/deeper/top.txt": "",
+			},
+			want: []string{"sub/deeper/top.txt", "top.txt"},
+		},
+		{
+			name: "middle slash",
+			files: map[string]string{
+				".gitignore": "doc/frotz\n",
+				"doc/frotz":  "", "doc/other": "", "a/doc/frotz": "",
+			},
+			want: []string{"a/doc/frotz", "doc/other"},
+		},
+		{
+			name: "double star",
+			files: map[string]string{
+				".gitignore": "docs/**/*.tmp\n",
+				"docs/a.tmp": "", "docs/x/b.tmp": "", "docs/x/y/c.tmp": "", "docs/keep.txt": "", "other/docs/d.tmp": "",
+			},
+			want: []string{"docs/keep.txt", "other/docs/d.tmp"},
+		},
+		{
+			name: "leading double star",
+			files: map[string]string{
+				".gitignore": "**/cache\n",
+				"cache/a":    "", "x/y/cache/b": "", "x/cached": "",
+			},
+			want: []string{"x/cached"},
+		},
+		{
+			name: "character classes",
+			files: map[string]string{
+				".gitignore": "file[0-9].txt\n[!a]x.txt\n",
+				"file1.txt":  "", "filea.txt": "", "bx.txt": "", "ax.txt": "",
+			},
+			want: []string{"ax.txt", "filea.txt"},
+		},
+		{
+			name: "escapes and question mark",
+			files: map[string]string{
+				".gitignore": "\\#notes\n\\!bang\nv?.bin\n",
+				"#notes":     "", "!bang": "", "v1.bin": "", "v10.bin": "",
+			},
+			want: []string{"v10.bin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGroot(t)
+			root := tempDir(t)
+			for path, content := range tt.files {
+				writeFile(t, filepath.Join(root, filepath.FromSlash(path)), content)
+			}
+			if err := SetRootFromPath(root); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			err := WalkFromRootGitAware(func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && d.Name() != ".gitignore" {
+					rel, err := filepath.Rel(root, path)
+					if err != nil {
+						return err
+					}
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("walked %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkFromRootGitAwareWithoutRoot(t *testing.T) {
+	resetGroot(t)
+	err := WalkFromRootGitAware(func(string, fs.DirEntry, error) error { return nil })
+	if !errors.Is(err, ErrRootNotSet) {
+		t.Errorf("WalkFromRootGitAware() = %v, want ErrRootNotSet", err)
+	}
+}
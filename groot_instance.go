@@ -0,0 +1,93 @@
+package groot
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ovila98/ers"
+)
+
+// Options configures NewStrict.
+type Options struct {
+	// EntryFile marks the root directory, as in SetRoot
+	EntryFile string
+	// EnvFiles are bare env filenames to load, as in SetRoot
+	EnvFiles []string
+	// EnvPaths are env files relative to each searched directory, as in
+	// SetRootWithEnvPaths
+	EnvPaths []string
+}
+
+// Groot is a resolved project root, independent of the package-level root.
+type Groot struct {
+	root     string
+	envFiles []string
+}
+
+// NewStrict resolves the root from opts, validates that it is an existing
+// directory, loads the env files and returns the initialized instance.
+// The package-level root is left untouched; env variables are loaded into the
+// process environment.
+//
+// Errors match (with errors.Is):
+//
+// - ErrNoEntryFile if opts.EntryFile is empty
+//
+// - ErrNoRootFound if no directory contains the entry file
+//
+// - ErrNotDirectory or fs.ErrNotExist if the root cannot be validated
+//
+// - ErrBadEnvsDefined if only invalid env names are given
+//
+// - ErrMissingEnvs if a requested env file is not found
+//
+// Unlike SetRoot, not requesting any env file is not an error.
+func NewStrict(opts Options) (*Groot, error) {
+	d, err := discoverRoot([]string{opts.EntryFile}, opts.EnvFiles, opts.EnvPaths)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	root, err := absCleanPath(d.root)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, ers.Wrap(err)
+	}
+	if !fi.IsDir() {
+		return nil, ers.Wrapf(ErrNotDirectory, "%s", root)
+	}
+
+	if d.definedEnvs {
+		if err := d.checkEnvs(); err != nil {
+			return nil, ers.Wrap(err)
+		}
+	}
+	var result SetRootResult
+	if err := loadEnvFiles(&result, d.envPaths); err != nil {
+		return nil, ers.Wrap(err)
+	}
+
+	return &Groot{root: root, envFiles: result.EnvFiles}, nil
+}
+
+// Root returns the root directory of the instance.
+func (g *Groot) Root() string {
+	return g.root
+}
+
+// EnvFiles returns the env files loaded when the instance was created.
+func (g *Groot) EnvFiles() []string {
+	return append([]string(nil), g.envFiles...)
+}
+
+// FromRoot joins the given path elements with the instance root.
+// If the first path is absolute, joins paths without root.
+func (g *Groot) FromRoot(path ...string) string {
+	if len(path) > 0 && filepath.IsAbs(path[0]) {
+		return filepath.Join(path...)
+	}
+	return filepath.Join(g.root, filepath.Join(path...))
+}
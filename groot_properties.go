@@ -0,0 +1,124 @@
+package groot
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ovila98/ers"
+)
+
+// isPropertiesFile reports whether the env file at path is a Java-style
+// .properties file, parsed with parseProperties instead of godotenv
+func isPropertiesFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".properties")
+}
+
+// parseProperties parses the subset of the java.util.Properties format used
+// in practice:
+//
+// - blank lines and lines starting with # or ! are ignored
+//
+// - the key ends at the first unescaped =, : or whitespace, which may be
+// surrounded by whitespace
+//
+// - a line ending with an odd number of backslashes continues on the next
+// one, whose leading whitespace is dropped
+//
+// - \t, \n, \r, \f, \uXXXX and \<any char> escapes are decoded in keys and
+// values
+func parseProperties(data []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for endsWithContinuation(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		line = strings.TrimSuffix(line, `\`)
+
+		// Find the end of the key, skipping escaped characters
+		end := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if strings.IndexByte("=: \t\f", line[j]) >= 0 {
+				end = j
+				break
+			}
+		}
+		key, err := unescapeProperty(line[:end])
+		if err != nil {
+			return nil, ers.Wrapf(err, "line %d", lineNo)
+		}
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return nil, ers.New("line %d: %q is not a valid env variable name", lineNo, key)
+		}
+
+		rest := strings.TrimLeft(line[end:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+		value, err := unescapeProperty(rest)
+		if err != nil {
+			return nil, ers.Wrapf(err, "line %d", lineNo)
+		}
+		props[key] = value
+	}
+	return props, nil
+}
+
+// endsWithContinuation reports whether line ends with an odd number of
+// backslashes
+func endsWithContinuation(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// unescapeProperty decodes the escapes of a .properties key or value
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", ers.New("malformed \\u escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", ers.Wrapf(err, "malformed \\u escape in %q", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
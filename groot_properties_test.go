@@ -0,0 +1,88 @@
+package groot
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{"equals", "a=1\nb = 2\n", map[string]string{"a": "1", "b": "2"}},
+		{"colon", "host: localhost\nport:8080\n", map[string]string{"host": "localhost", "port": "8080"}},
+		{"whitespace", "name value with spaces\n", map[string]string{"name": "value with spaces"}},
+		{"comments", "# comment\n! other\n\n  a=1\n", map[string]string{"a": "1"}},
+		{"continuation", "list = one, \\\n       two, \\\n       three\n", map[string]string{"list": "one, two, three"}},
+		{"escaped backslash ends line", "path = C:\\\\\nnext = 1\n", map[string]string{"path": `C:\`, "next": "1"}},
+		{"unicode escape", "greeting = caf\\u00e9 \\u0041\n", map[string]string{"greeting": "café A"}},
+		{"escapes", "tab = a\\tb\nnl = a\\nb\nother = \\#\\q\n", map[string]string{"tab": "a\tb", "nl": "a\nb", "other": "#q"}},
+		{"escaped separator in key", "a\\:b = 1\n", map[string]string{"a:b": "1"}},
+		{"empty value", "a=\nb\n", map[string]string{"a": "", "b": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProperties([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProperties(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePropertiesErrors(t *testing.T) {
+	for _, data := range []string{
+		"= value\n",
+		"a\\=b = 1\n",
+		"a = \\u00\n",
+		"a = \\uzzzz\n",
+	} {
+		if got, err := parseProperties([]byte(data)); err == nil {
+			t.Errorf("parseProperties(%q) = %q, want error", data, got)
+		}
+	}
+}
+
+func TestReadEnvFileOptionsApplyToResolveAndValidate(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	writeFile(t, filepath.Join(root, "app.properties"), "db.host: localhost\n")
+	writeFile(t, filepath.Join(root, "secrets.enc.env"), "ENCRYPTED")
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultEnvOptions()
+	opts.Decryptor = func(ciphertext []byte) ([]byte, error) {
+		if !bytes.Equal(ciphertext, []byte("ENCRYPTED")) {
+			return nil, errors.New("bad ciphertext")
+		}
+		return []byte("TOKEN=secret\n"), nil
+	}
+	SetEnvOptions(opts)
+
+	resolved, err := ResolveEnvWithSources("app.properties", "secrets.enc.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]EnvValue{
+		"db.host": {Value: "localhost", Source: filepath.Join(root, "app.properties")},
+		"TOKEN":   {Value: "secret", Source: filepath.Join(root, "secrets.enc.env")},
+	}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Errorf("ResolveEnvWithSources() = %v, want %v", resolved, want)
+	}
+	if err := ValidateEnvSchema([]string{"db.host", "TOKEN"}, "app.properties", "secrets.enc.env"); err != nil {
+		t.Errorf("ValidateEnvSchema() = %v, want nil", err)
+	}
+	if err := ValidateEnvSchema([]string{"db.host"}, "secrets.enc.env"); err == nil {
+		t.Error("ValidateEnvSchema() = nil, want error for TOKEN")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !plan9
+
+package groot
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isReadOnlyFSError reports whether err means the filesystem is mounted
+// read-only
+func isReadOnlyFSError(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
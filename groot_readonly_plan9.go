@@ -0,0 +1,9 @@
+//go:build plan9
+
+package groot
+
+// isReadOnlyFSError reports whether err means the filesystem is mounted
+// read-only. Plan 9 has no EROFS, so the error is left to the caller.
+func isReadOnlyFSError(err error) bool {
+	return false
+}
@@ -0,0 +1,140 @@
+package groot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ovila98/ers"
+)
+
+// ErrUnknownRoot indicates no root was registered under the requested name
+var ErrUnknownRoot = errors.New("unknown named root")
+
+// Named roots registered with RegisterRoot, separate from the default root.
+var (
+	namedRootsMu sync.RWMutex
+	namedRoots   = make(map[string]string)
+)
+
+// RegisterRoot registers path as the root named name (e.g. "api", "worker").
+// Relative paths are resolved from the working directory.
+// Registering an existing name replaces its path.
+// Returns error if name is empty or path is not an existing directory.
+func RegisterRoot(name, path string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ers.New("root name cannot be empty")
+	}
+
+	abs, err := absCleanPath(path)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	if !fi.IsDir() {
+		return ers.Wrapf(ErrNotDirectory, "%s", abs)
+	}
+
+	namedRootsMu.Lock()
+	defer namedRootsMu.Unlock()
+	namedRoots[name] = abs
+	return nil
+}
+
+// GetNamedRoot returns the path registered under name.
+// Returns ErrUnknownRoot if name is not registered.
+func GetNamedRoot(name string) (string, error) {
+	namedRootsMu.RLock()
+	defer namedRootsMu.RUnlock()
+	root, ok := namedRoots[strings.TrimSpace(name)]
+	if !ok {
+		return "", ers.Wrapf(ErrUnknownRoot, "%s", name)
+	}
+	return root, nil
+}
+
+// FromNamedRoot joins the given path elements with the root registered under name.
+// Returns ErrUnknownRoot if name is not registered.
+func FromNamedRoot(name string, path ...string) (string, error) {
+	root, err := GetNamedRoot(name)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return filepath.Join(root, filepath.Join(path...)), nil
+}
+
+// EnclosingRoot returns the deepest of candidates that is path itself or one of
+// its ancestors, comparing canonical paths (see CanonPath).
+// Returns error if no candidate encloses path.
+func EnclosingRoot(path string, candidates []string) (string, error) {
+	cleanPath, err := CanonPath(path)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+
+	best, bestLen := "", -1
+	for _, candidate := range candidates {
+		cleanCandidate, err := CanonPath(candidate)
+		if err != nil {
+			return "", ers.Wrap(err)
+		}
+		if isWithin(cleanCandidate, cleanPath) && len(cleanCandidate) > bestLen {
+			best, bestLen = candidate, len(cleanCandidate)
+		}
+	}
+	if bestLen < 0 {
+		return "", ers.New("no candidate root encloses %s", path)
+	}
+	return best, nil
+}
+
+// Markers used by SameRoot when none are given
+var defaultRootMarkers = []string{".git", "go.mod", ProjectRootSentinel}
+
+// SameRoot finds, for pathA and pathB, the nearest enclosing directory
+// containing any of markers (.git, go.mod or .project-root if none are given)
+// and reports whether both paths share it, along with that root.
+// Returns an error matching ErrNoRootFound if either path has no enclosing root.
+func SameRoot(pathA, pathB string, markers ...string) (bool, string, error) {
+	if len(markers) == 0 {
+		markers = defaultRootMarkers
+	}
+
+	rootA, err := markerRootOf(pathA, markers)
+	if err != nil {
+		return false, "", ers.Wrap(err)
+	}
+	rootB, err := markerRootOf(pathB, markers)
+	if err != nil {
+		return false, "", ers.Wrap(err)
+	}
+	if rootA != rootB {
+		return false, "", nil
+	}
+	return true, rootA, nil
+}
+
+// markerRootOf returns the nearest directory enclosing path that contains any
+// of markers, starting from path itself if it is a directory
+func markerRootOf(path string, markers []string) (string, error) {
+	start, err := CanonPath(path)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	if fi, err := os.Stat(start); err == nil && !fi.IsDir() {
+		start = filepath.Dir(start)
+	}
+	root, _ := findRootFrom(start, func(dir string) (bool, error) {
+		return hasAnyFile(dir, markers), nil
+	})
+	if root == "" {
+		return "", ers.Wrapf(ErrNoRootFound, "for %s", path)
+	}
+	return root, nil
+}
@@ -0,0 +1,92 @@
+package groot
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/ovila98/ers"
+)
+
+// Matches a URL scheme prefix; single letters are left to Windows drive letters
+var specSchemeRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]+):`)
+
+// SetRootFromSpec sets the root from a single string spec, typically read from
+// a config file or a flag:
+//
+// - file:///abs/path (or file://localhost/...) sets the given path
+//
+// - git: or git:<dir> sets the nearest git root above the project dir or dir
+//
+// - gomod: or gomod:<dir> sets the nearest go.mod directory likewise
+//
+// - env:VARNAME sets the path held by the VARNAME env var
+//
+// - anything else is a plain path, set as SetRootFromPath does
+//
+// Relative directories are resolved from the project directory.
+// Returns error for unknown schemes and when the selected strategy fails.
+func SetRootFromSpec(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return ers.New("spec cannot be empty")
+	}
+	m := specSchemeRegex.FindStringSubmatch(spec)
+	if m == nil {
+		return ers.Wrap(SetRootFromPath(spec))
+	}
+	rest := spec[len(m[0]):]
+
+	switch strings.ToLower(m[1]) {
+	case "file":
+		u, err := url.Parse(spec)
+		if err != nil {
+			return ers.Wrapf(err, "invalid spec %q", spec)
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return ers.New("spec %q: remote file hosts are not supported", spec)
+		}
+		path := u.Path
+		// file:///C:/dir has path /C:/dir
+		if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+			path = path[1:]
+		}
+		return ers.Wrap(SetRootFromPath(path))
+	case "git":
+		return ers.Wrap(setRootFromSpecSearch(rest, FindGitRootFrom, "no git root found", SourceGit))
+	case "gomod":
+		return ers.Wrap(setRootFromSpecSearch(rest, FindGoModRootFrom, "no go.mod found", SourceMarker))
+	case "env":
+		if rest == "" {
+			return ers.New("spec %q: env var name missing", spec)
+		}
+		path := strings.TrimSpace(os.Getenv(rest))
+		if path == "" {
+			return ers.New("spec %q: env var %s is not set", spec, rest)
+		}
+		return ers.Wrap(SetRootFromPath(path))
+	default:
+		return ers.New("spec %q: unknown scheme %q", spec, m[1])
+	}
+}
+
+// setRootFromSpecSearch sets the root found by find from startDir, or from
+// the search directory if empty
+func setRootFromSpecSearch(startDir string, find func(startPath string) string, notFound string, source RootSource) error {
+	var err error
+	if startDir = strings.TrimSpace(startDir); startDir == "" {
+		startDir, err = getSearchDir()
+	} else {
+		startDir, err = resolveFromProjectDir(startDir)
+	}
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	root := find(startDir)
+	if root == "" {
+		return ers.New("%s above %s", notFound, startDir)
+	}
+	return setRootPath(root, source)
+}
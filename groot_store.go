@@ -0,0 +1,299 @@
+package groot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ovila98/ers"
+)
+
+// RootStore persists the project root path.
+// Save with an empty root clears the stored value.
+type RootStore interface {
+	Load() (string, error)
+	Save(root string) error
+}
+
+// ErrRootLocked indicates the root cannot change because LockRoot was called
+var ErrRootLocked = errors.New("root is locked")
+
+// ErrStaleRoot indicates a stored root no longer exists as a directory
+var ErrStaleRoot = errors.New("stored root no longer exists")
+
+// EnvStore stores the root in the environment variable set by SetGrootKey.
+// It is the default store.
+type EnvStore struct{}
+
+// Load returns the root stored in the environment.
+func (EnvStore) Load() (string, error) {
+	return os.Getenv(grootEnv), nil
+}
+
+// Save sets the root in the environment, or unsets it if root is empty.
+func (EnvStore) Save(root string) error {
+	if root == "" {
+		return ers.Wrap(os.Unsetenv(grootEnv))
+	}
+	return ers.Wrap(os.Setenv(grootEnv, root))
+}
+
+// FileStore stores the root in a file (e.g. ".groot-cache") so that later
+// launches can reuse it without running discovery again.
+type FileStore struct {
+	Path string
+}
+
+// Load returns the root stored in the file.
+// Returns an empty root if the file does not exist and ErrStaleRoot if the
+// stored root is no longer an existing directory.
+func (s FileStore) Load() (string, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	root := strings.TrimSpace(string(data))
+	if root == "" {
+		return "", nil
+	}
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return "", ers.Wrap(ErrStaleRoot)
+	}
+	return root, nil
+}
+
+// Save writes the root to the file, or removes the file if root is empty.
+func (s FileStore) Save(root string) error {
+	if root == "" {
+		err := os.Remove(s.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return ers.Wrap(err)
+	}
+	return ers.Wrap(os.WriteFile(s.Path, []byte(root+"\n"), 0o644))
+}
+
+// MemoryStore stores the root in memory.
+// The zero value is ready to use.
+type MemoryStore struct {
+	mu   sync.Mutex
+	root string
+}
+
+// Load returns the root stored in memory.
+func (s *MemoryStore) Load() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.root, nil
+}
+
+// Save stores the root in memory.
+func (s *MemoryStore) Save(root string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.root = root
+	return nil
+}
+
+// rootMu guards the root store state below.
+var rootMu sync.RWMutex
+
+// Current root store, read by GetRoot.
+var rootStore RootStore = EnvStore{}
+
+// Additional stores written alongside rootStore.
+var mirrorStores []RootStore
+
+// Whether root changes are rejected with ErrRootLocked.
+var rootLocked bool
+
+// Incremented on every root change, for RootToken.
+var rootGeneration uint64
+
+// Root cache, avoiding a store lookup on every GetRoot call.
+var (
+	cachedRoot string
+	rootCached bool
+)
+
+// SetStore changes the backend used to persist the root path.
+// The root is read from store only, but saved to store and every mirror
+// (e.g. while migrating from one store to another).
+// Returns error if any store is nil and ErrRootLocked if the root is locked.
+func SetStore(store RootStore, mirrors ...RootStore) error {
+	if store == nil {
+		return ers.New("store cannot be nil")
+	}
+	for _, mirror := range mirrors {
+		if mirror == nil {
+			return ers.New("mirror store cannot be nil")
+		}
+	}
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	if rootLocked {
+		return ers.Wrap(ErrRootLocked)
+	}
+	rootStore = store
+	mirrorStores = mirrors
+	rootCached = false
+	rootGeneration++
+	return nil
+}
+
+// CheckRootConsistency compares the root held by the store and each mirror set
+// through SetStore, returning an error describing any mismatch.
+// Returns nil when no mirror is in use.
+func CheckRootConsistency() error {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	if len(mirrorStores) == 0 {
+		return nil
+	}
+	root, err := rootStore.Load()
+	if err != nil {
+		return ers.Wrap(err)
+	}
+	var mismatches []string
+	for i, mirror := range mirrorStores {
+		mirrorRoot, err := mirror.Load()
+		if err != nil {
+			return ers.Wrapf(err, "mirror store %d", i)
+		}
+		if mirrorRoot != root {
+			mismatches = append(mismatches, fmt.Sprintf("mirror store %d (%T) has %q", i, mirror, mirrorRoot))
+		}
+	}
+	if len(mismatches) > 0 {
+		return ers.New("root stores out of sync: store (%T) has %q; %s",
+			rootStore, root, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// RootSource tells how the root was established.
+type RootSource string
+
+const (
+	SourceEntryFile RootSource = "entry file"
+	SourceGit       RootSource = "git"
+	SourcePath      RootSource = "path"
+	SourcePredicate RootSource = "predicate"
+	SourceTest      RootSource = "test"
+	SourceMarker    RootSource = "marker"
+	SourcePlugin    RootSource = "plugin"
+	SourceBuild     RootSource = "build"
+	SourceFallback  RootSource = "fallback"
+	// SourceStore means the root was found in the store without being set
+	// by this process (e.g. an inherited env var or a cache file).
+	SourceStore RootSource = "store"
+)
+
+// Source of the root last set by this process.
+var rootSource RootSource
+
+// GetRootSource returns how the current root was established.
+// Returns an empty source if root is not set.
+func GetRootSource() RootSource {
+	if GetRoot() == "" {
+		return ""
+	}
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	if rootSource == "" {
+		return SourceStore
+	}
+	return rootSource
+}
+
+// loadRoot returns the cached root, reading it from the store on first use.
+// Returns an empty root if the store cannot provide it. An empty root is not
+// cached, so a root set behind the package's back (e.g. the env var being
+// exported later) is still picked up.
+func loadRoot() string {
+	rootMu.RLock()
+	if rootCached {
+		defer rootMu.RUnlock()
+		return cachedRoot
+	}
+	rootMu.RUnlock()
+
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	if rootCached {
+		return cachedRoot
+	}
+	root, err := rootStore.Load()
+	if err != nil || root == "" {
+		return ""
+	}
+	cachedRoot, rootCached = root, true
+	return root
+}
+
+// LockRoot rejects every later root change (SetRoot*, ClearRoot, SetStore,
+// SetGrootKey) with ErrRootLocked until UnlockRoot is called.
+func LockRoot() {
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	rootLocked = true
+}
+
+// UnlockRoot allows root changes again after LockRoot.
+func UnlockRoot() {
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	rootLocked = false
+}
+
+// IsRootLocked reports whether LockRoot is in effect.
+func IsRootLocked() bool {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return rootLocked
+}
+
+// setRootPath saves root to the current store and its mirrors and records its source.
+// Returns ErrRootLocked if the root is locked.
+func setRootPath(root string, source RootSource) error {
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	if rootLocked {
+		return ers.Wrap(ErrRootLocked)
+	}
+	rootCached = false
+	if err := rootStore.Save(root); err != nil {
+		return ers.Wrap(err)
+	}
+	for _, mirror := range mirrorStores {
+		if err := mirror.Save(root); err != nil {
+			return ers.Wrap(err)
+		}
+	}
+	cachedRoot, rootCached = root, root != ""
+	rootSource = source
+	rootGeneration++
+	return nil
+}
+
+// RootToken returns an opaque token that changes whenever the root changes.
+// Store it and pass it to RootTokenChanged to know when state derived from the
+// root must be recomputed.
+func RootToken() string {
+	root := GetRoot()
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return fmt.Sprintf("%d:%s", rootGeneration, root)
+}
+
+// RootTokenChanged reports whether the root changed since token was obtained
+// from RootToken.
+func RootTokenChanged(token string) bool {
+	return RootToken() != token
+}
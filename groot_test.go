@@ -0,0 +1,799 @@
+package groot
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetGroot clears the root and restores the package state tests change
+func resetGroot(t testing.TB) {
+	t.Helper()
+	t.Setenv(grootEnv, "")
+	if err := ClearRoot(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ClearRoot()
+		SetEnvOptions(DefaultEnvOptions())
+		SetStrictSearch(false)
+		SetIdempotent(false)
+		SetFallbackChain()
+		OnEnvSet(nil)
+		ClearGitRootCache()
+		executable = os.Executable
+		mainFile = GetMainFile
+	})
+}
+
+// tempDir returns a new temporary directory with symlinks resolved, so paths
+// compare equal to the ones groot reports (e.g. /private/var on macOS)
+func tempDir(t testing.TB) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// writeFile creates the file at path, with its parent directories
+func writeFile(t testing.TB, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// symlink creates a symbolic link, skipping the test where they are unsupported
+func symlink(t testing.TB, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+}
+
+// useProjectDir makes dir the project directory, as if the running executable
+// were a standalone binary built in dir
+func useProjectDir(t testing.TB, dir string) {
+	t.Helper()
+	exe := filepath.Join(dir, "app")
+	writeFile(t, exe, "")
+	executable = func() (string, error) { return exe, nil }
+	// Test binaries live in the temp dir, which marks them as 'go run' builds
+	tmp := t.TempDir()
+	for _, key := range []string{"TMPDIR", "TMP", "TEMP"} {
+		t.Setenv(key, tmp)
+	}
+	t.Cleanup(func() { executable = os.Executable })
+}
+
+func TestGetRootSeesRootSetAfterEmptyLookup(t *testing.T) {
+	resetGroot(t)
+	if root := GetRoot(); root != "" {
+		t.Fatalf("GetRoot() = %q, want empty", root)
+	}
+	dir := tempDir(t)
+	os.Setenv(grootEnv, dir)
+	if root := GetRoot(); root != dir {
+		t.Fatalf("GetRoot() = %q, want %q", root, dir)
+	}
+}
+
+func BenchmarkFromRoot(b *testing.B) {
+	resetGroot(b)
+	if err := SetRootFromPath(tempDir(b)); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			FromRoot("config", "app.yaml")
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// Read the root from the store, as before the cache
+			rootMu.Lock()
+			rootCached = false
+			rootMu.Unlock()
+			FromRoot("config", "app.yaml")
+		}
+	})
+}
+
+func TestGetRootSubdir(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	if err := os.MkdirAll(filepath.Join(root, "config", "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "file.txt"), "")
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"config":            filepath.Join(root, "config"),
+		"config/nested/":    filepath.Join(root, "config", "nested"),
+		"config/../config":  filepath.Join(root, "config"),
+		"./config/./nested": filepath.Join(root, "config", "nested"),
+	} {
+		got, err := GetRootSubdir(name)
+		if err != nil || got != want {
+			t.Errorf("GetRootSubdir(%q) = %q, %v, want %q, nil", name, got, err, want)
+		}
+	}
+
+	invalid := []string{"", "  ", ".", "..", "../x", "config/../..", root, filepath.Join(root, "config")}
+	if runtime.GOOS == "windows" {
+		invalid = append(invalid, "C:config", `\\server\share\config`)
+	}
+	for _, name := range invalid {
+		if got, err := GetRootSubdir(name); err == nil {
+			t.Errorf("GetRootSubdir(%q) = %q, want error", name, got)
+		}
+	}
+	if _, err := GetRootSubdir("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("GetRootSubdir(missing) error = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := GetRootSubdir("file.txt"); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("GetRootSubdir(file.txt) error = %v, want ErrNotDirectory", err)
+	}
+}
+
+func TestFromScopedRoot(t *testing.T) {
+	resetGroot(t)
+	if _, err := FromScopedRoot("plugins"); !errors.Is(err, ErrRootNotSet) {
+		t.Errorf("FromScopedRoot() without root = %v, want ErrRootNotSet", err)
+	}
+	root := tempDir(t)
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		base string
+		path []string
+		want string
+	}{
+		{"plugins/foo", []string{"config.json"}, filepath.Join(root, "plugins", "foo", "config.json")},
+		{"plugins/../shared", nil, filepath.Join(root, "shared")},
+		{".", []string{"a", "b"}, filepath.Join(root, "a", "b")},
+	} {
+		got, err := FromScopedRoot(tt.base, tt.path...)
+		if err != nil || got != tt.want {
+			t.Errorf("FromScopedRoot(%q, %q) = %q, %v, want %q, nil", tt.base, tt.path, got, err, tt.want)
+		}
+	}
+
+	invalid := []string{"..", "../x", "plugins/../..", root}
+	if runtime.GOOS == "windows" {
+		// Volume-relative and UNC bases are not absolute but still leave root
+		invalid = append(invalid, "C:plugins", "C:", `\\server\share\plugins`)
+	}
+	for _, base := range invalid {
+		if got, err := FromScopedRoot(base, "config.json"); err == nil {
+			t.Errorf("FromScopedRoot(%q) = %q, want error", base, got)
+		}
+	}
+}
+
+func TestIsInRoot(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		root:                                 true,
+		filepath.Join(root, "a", "b"):        true,
+		filepath.Join(root, "..cache"):       true,
+		filepath.Join(root, "..cache", "x"):  true,
+		filepath.Join(root, ".."):            false,
+		filepath.Join(root, "..", "sibling"): false,
+		filepath.Join(root, "a", "..", ".."): false,
+		root + "-other":                      false,
+	} {
+		if got := IsInRoot(path); got != want {
+			t.Errorf("IsInRoot(%q) = %v, want %v", path, got, want)
+		}
+		if got := IsInRootWith(root, path); got != want {
+			t.Errorf("IsInRootWith(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestClearGitRootCacheInvalidatesNegativeResult(t *testing.T) {
+	resetGroot(t)
+	repo := tempDir(t)
+	start := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(start, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if root := FindGitRootFrom(start); root != "" {
+		t.Skipf("temp dir is inside git repository %s", root)
+	}
+
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if root := FindGitRootFrom(start); root != "" {
+		t.Fatalf("FindGitRootFrom() = %q, want cached empty result", root)
+	}
+	ClearGitRootCache()
+	if root := FindGitRootFrom(start); root != repo {
+		t.Fatalf("FindGitRootFrom() after ClearGitRootCache = %q, want %q", root, repo)
+	}
+}
+
+func BenchmarkFindGitRootFrom(b *testing.B) {
+	resetGroot(b)
+	repo := tempDir(b)
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		b.Fatal(err)
+	}
+	// 1000 files spread over 100 directories, 4 levels deep
+	var files []string
+	for i := 0; i < 100; i++ {
+		dir := filepath.Join(repo, fmt.Sprintf("a%d", i%5), fmt.Sprintf("b%d", i%20), fmt.Sprintf("c%d", i), "d")
+		for j := 0; j < 10; j++ {
+			path := filepath.Join(dir, fmt.Sprintf("file%d.go", j))
+			writeFile(b, path, "")
+			files = append(files, path)
+		}
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		ClearGitRootCache()
+		for i := 0; i < b.N; i++ {
+			for _, path := range files {
+				if FindGitRootFrom(path) != repo {
+					b.Fatalf("FindGitRootFrom(%q) != %q", path, repo)
+				}
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ClearGitRootCache()
+			for _, path := range files {
+				if FindGitRootFrom(path) != repo {
+					b.Fatalf("FindGitRootFrom(%q) != %q", path, repo)
+				}
+			}
+		}
+	})
+}
+
+func TestSetRootUnreadableDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("permission bits are not enforced for this user")
+	}
+	resetGroot(t)
+	root := tempDir(t)
+	projectDir := filepath.Join(root, "cmd")
+	writeFile(t, filepath.Join(root, "app.id"), "")
+	writeFile(t, filepath.Join(root, "private", ".env"), "GROOT_TEST_A=root\n")
+	locked := filepath.Join(projectDir, "private")
+	writeFile(t, filepath.Join(locked, ".env"), "GROOT_TEST_A=locked\n")
+	if err := os.Chmod(locked, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+	useProjectDir(t, projectDir)
+	unsetEnv(t, "GROOT_TEST_A")
+
+	t.Run("skipped by default", func(t *testing.T) {
+		if err := SetRootWithEnvPaths("app.id", nil, "private/.env"); err != nil {
+			t.Fatal(err)
+		}
+		if got := os.Getenv("GROOT_TEST_A"); got != "root" {
+			t.Errorf("GROOT_TEST_A = %q, want %q", got, "root")
+		}
+	})
+	t.Run("fails when strict", func(t *testing.T) {
+		SetStrictSearch(true)
+		err := SetRootWithEnvPaths("app.id", nil, "private/.env")
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("SetRootWithEnvPaths() = %v, want fs.ErrPermission", err)
+		}
+	})
+}
+
+func TestGetRelativeToRootDifferentVolume(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("drive letters are Windows only")
+	}
+	resetGroot(t)
+	root := tempDir(t)
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	drive := `Z:`
+	if strings.EqualFold(filepath.VolumeName(root), drive) {
+		drive = `Y:`
+	}
+	path := drive + `\project\file.txt`
+
+	if _, err := GetRelativeToRoot(path); !errors.Is(err, ErrDifferentVolume) {
+		t.Errorf("GetRelativeToRoot(%q) = %v, want ErrDifferentVolume", path, err)
+	}
+	if _, err := GetRelativeToRootWith(root, path); !errors.Is(err, ErrDifferentVolume) {
+		t.Errorf("GetRelativeToRootWith(%q) = %v, want ErrDifferentVolume", path, err)
+	}
+	if IsInRoot(path) {
+		t.Errorf("IsInRoot(%q) = true, want false", path)
+	}
+}
+
+// chdir changes the working directory for the duration of the test
+func chdir(t testing.TB, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestGetProjectDirResolvesExecutable(t *testing.T) {
+	installDir := tempDir(t)
+	exe := filepath.Join(installDir, "libexec", "tool")
+	writeFile(t, exe, "")
+
+	t.Run("symlink in PATH", func(t *testing.T) {
+		resetGroot(t)
+		binDir := tempDir(t)
+		link := filepath.Join(binDir, "tool")
+		symlink(t, exe, link)
+		useProjectDir(t, binDir)
+		executable = func() (string, error) { return link, nil }
+
+		checkExecutableDirs(t, filepath.Dir(exe))
+	})
+	t.Run("relative path", func(t *testing.T) {
+		resetGroot(t)
+		useProjectDir(t, installDir)
+		chdir(t, installDir)
+		executable = func() (string, error) { return filepath.Join("libexec", "tool"), nil }
+
+		checkExecutableDirs(t, filepath.Dir(exe))
+	})
+}
+
+// checkExecutableDirs checks that the executable and project dirs are want
+func checkExecutableDirs(t *testing.T, want string) {
+	t.Helper()
+	if dir, err := GetExecutableDir(); err != nil || dir != want {
+		t.Errorf("GetExecutableDir() = %q, %v, want %q, nil", dir, err, want)
+	}
+	if dir, err := GetProjectDir(); err != nil || dir != want {
+		t.Errorf("GetProjectDir() = %q, %v, want %q, nil", dir, err, want)
+	}
+}
+
+func TestIterateThroughPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/a/b/../c", []string{"/a/c", "/a", "/"}},
+		{"/a/b/", []string{"/a/b", "/a", "/"}},
+		{"/a/./b", []string{"/a/b", "/a", "/"}},
+		{"/a//b/..", []string{"/a", "/"}},
+		{"/", []string{"/"}},
+	}
+	for _, tt := range tests {
+		path := filepath.FromSlash(tt.path)
+		want := make([]string, len(tt.want))
+		for i, p := range tt.want {
+			want[i] = filepath.FromSlash(p)
+		}
+		if got := IterateThroughPath(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("IterateThroughPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestIsDirectChildOfRoot(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		filepath.Join(root, "plugins"):                              true,
+		filepath.Join(root, "plugins") + string(filepath.Separator): true,
+		filepath.Join(root, "plugins", "auth"):                      false,
+		root:                                                        false,
+		filepath.Join(root, "plugins", ".."):                        false,
+		filepath.Dir(root):                                          false,
+	} {
+		if got := IsDirectChildOfRoot(path); got != want {
+			t.Errorf("IsDirectChildOfRoot(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestIsTemporaryExecutable(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		// go run builds
+		{"/tmp/go-build1234/b001/exe/main", true},
+		{"/var/folders/xy/abc123/T/go-build5678/b001/exe/app", true},
+		{`C:\Users\gopher\AppData\Local\Temp\go-build9012\b001\exe\app.exe`, true},
+		{"/home/gopher/.cache/go-build/ab/cdef-d/app", true},
+		// go test binaries
+		{"/home/gopher/work/pkg.test", true},
+		{"/Users/gopher/src/app/app.test", true},
+		{`C:\src\app\app.test.exe`, true},
+		{`C:\src\app\APP.TEST.EXE`, true},
+		// installed binaries
+		{"/usr/local/bin/app", false},
+		{"/Users/gopher/go/bin/app", false},
+		{`C:\Program Files\App\app.exe`, false},
+		{"/opt/testing/app", false},
+		{"", false},
+		{"   ", false},
+	}
+	for _, tt := range tests {
+		if got := IsTemporaryExecutable(tt.path); got != tt.want {
+			t.Errorf("IsTemporaryExecutable(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	tempDir := os.TempDir()
+	if path := filepath.Join(tempDir, "build", "app"); !IsTemporaryExecutable(path) {
+		t.Errorf("IsTemporaryExecutable(%q) = false, want true under os.TempDir()", path)
+	}
+	if path := filepath.Join(tempDir+"-other", "app"); IsTemporaryExecutable(path) {
+		t.Errorf("IsTemporaryExecutable(%q) = true, want false outside os.TempDir()", path)
+	}
+}
+
+func TestRootComparisonsAcrossPrivateVar(t *testing.T) {
+	if runtime.GOOS != "darwin" {
+		t.Skip("/var is a symlink to /private/var on macOS only")
+	}
+	resetGroot(t)
+	// t.TempDir is under /var/folders, reported as /private/var/folders once
+	// resolved
+	dir := t.TempDir()
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dir, "/var/") || resolved != "/private"+dir {
+		t.Skipf("temp dir %s is not under /var", dir)
+	}
+
+	for _, tt := range []struct{ root, other string }{{dir, resolved}, {resolved, dir}} {
+		if err := SetRootFromPath(tt.root); err != nil {
+			t.Fatal(err)
+		}
+		if !IsRoot(tt.other) {
+			t.Errorf("root %s: IsRoot(%q) = false, want true", tt.root, tt.other)
+		}
+		sub := filepath.Join(tt.other, "sub", "file.txt")
+		if !IsInRoot(sub) {
+			t.Errorf("root %s: IsInRoot(%q) = false, want true", tt.root, sub)
+		}
+		if rel, err := GetRelativeToRoot(sub); err != nil || rel != filepath.Join("sub", "file.txt") {
+			t.Errorf("root %s: GetRelativeToRoot(%q) = %q, %v, want %q", tt.root, sub, rel, err, "sub/file.txt")
+		}
+	}
+}
+
+func TestSeedRootFromDefaultRoot(t *testing.T) {
+	buildRoot := tempDir(t)
+	defer func(saved string) { defaultRoot = saved }(defaultRoot)
+	defaultRoot = buildRoot
+
+	t.Run("seeds root", func(t *testing.T) {
+		resetGroot(t)
+		unsetEnv(t, autodetectEnv)
+		seedRoot()
+		if got := GetRoot(); got != buildRoot {
+			t.Errorf("GetRoot() = %q, want %q", got, buildRoot)
+		}
+		if got := GetRootSource(); got != SourceBuild {
+			t.Errorf("GetRootSource() = %q, want %q", got, SourceBuild)
+		}
+	})
+	t.Run("invalid root skipped", func(t *testing.T) {
+		file := filepath.Join(buildRoot, "file.txt")
+		writeFile(t, file, "")
+		for _, path := range []string{filepath.Join(buildRoot, "missing"), file} {
+			resetGroot(t)
+			unsetEnv(t, autodetectEnv)
+			defaultRoot = path
+			seedRoot()
+			if got := GetRoot(); got != "" {
+				t.Errorf("GetRoot() with defaultRoot %q = %q, want empty", path, got)
+			}
+		}
+		defaultRoot = buildRoot
+	})
+	t.Run("env var wins", func(t *testing.T) {
+		resetGroot(t)
+		envRoot := tempDir(t)
+		t.Setenv(grootEnv, envRoot)
+		seedRoot()
+		if got := GetRoot(); got != envRoot {
+			t.Errorf("GetRoot() = %q, want %q", got, envRoot)
+		}
+	})
+}
+
+func TestGetSearchDirFallsBackToWorkingDir(t *testing.T) {
+	resetGroot(t)
+	repo := tempDir(t)
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	wd := filepath.Join(repo, "cmd")
+	if err := os.Mkdir(wd, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, wd)
+	// A stripped binary: the executable is known but not the main source file
+	mainFile = func() (string, error) { return "", errors.New("main *.go file not found") }
+
+	if _, err := GetProjectDir(); err == nil {
+		t.Fatal("GetProjectDir() = nil error, want the main file failure")
+	}
+	if dir, err := getSearchDir(); err != nil || dir != wd {
+		t.Errorf("getSearchDir() = %q, %v, want %q, nil", dir, err, wd)
+	}
+	if err := SetRootFromGit(); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetRoot(); got != repo {
+		t.Errorf("GetRoot() = %q, want %q", got, repo)
+	}
+}
+
+// configKeyTest is a case of TestTOMLConfigKey and TestYAMLConfigKey
+type configKeyTest struct {
+	name      string
+	data      string
+	want      string
+	wantFound bool
+}
+
+// checkConfigKey runs tests against the root key reader read
+func checkConfigKey(t *testing.T, read func(data []byte, key string) (string, bool, error), tests []configKeyTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := read([]byte(tt.data), "root")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("read(%q) = %q, %v, want %q, %v", tt.data, got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestTOMLConfigKey(t *testing.T) {
+	checkConfigKey(t, tomlConfigKey, []configKeyTest{
+		{"basic string", "root = \"sub\"\n", "sub", true},
+		{"escapes", `root = "a\tb"`, "a\tb", true},
+		{"literal string", `root = 'C:\dir'`, `C:\dir`, true},
+		{"quoted key", `"root" = "sub"`, "sub", true},
+		{"comments", "# header\nroot = \"sub\" # trailing = comment\n", "sub", true},
+		{"CRLF", "name = \"x\"\r\nroot = \"sub\"\r\n", "sub", true},
+		{"bare value", "root = sub", "sub", true},
+		{"multi-line array before", "deps = [\n  \"a\",\n  \"b]\", # ] in a comment\n]\nroot = \"sub\"\n", "sub", true},
+		{"nested arrays before", "matrix = [\n  [1, 2],\n  [3, 4],\n]\nroot = \"sub\"\n", "sub", true},
+		{"multi-line basic string before", "desc = \"\"\"\nroot = \"wrong\"\n\\\"\"\"\nnot a key\n\"\"\"\nroot = \"sub\"\n", "sub", true},
+		{"multi-line literal string before", "desc = '''\nroot = 'wrong'\n'''\nroot = \"sub\"\n", "sub", true},
+		{"multi-line basic string", "root = \"\"\"\n/srv/app\"\"\"\n", "/srv/app", true},
+		{"multi-line literal string", "root = '''\n/srv/app'''\n", "/srv/app", true},
+		{"line ending backslash", "root = \"\"\"\\\n    /srv/\\\n    app\"\"\"\n", "/srv/app", true},
+		{"table", "name = \"x\"\n[server]\nroot = \"sub\"\n", "", false},
+		{"array of tables", "[[servers]]\nroot = \"sub\"\n", "", false},
+		{"missing", "name = \"x\"\n", "", false},
+	})
+
+	for _, data := range []string{
+		"garbage\nroot = \"sub\"\n",
+		"deps = [\n  \"a\",\nroot = \"sub\"\n",
+		"root = \"\"\"\n/srv/app\n",
+		"root = \"unterminated\n",
+	} {
+		if got, _, err := tomlConfigKey([]byte(data), "root"); err == nil {
+			t.Errorf("tomlConfigKey(%q) = %q, want error", data, got)
+		}
+	}
+}
+
+func TestYAMLConfigKey(t *testing.T) {
+	checkConfigKey(t, yamlConfigKey, []configKeyTest{
+		{"plain", "root: sub\n", "sub", true},
+		{"double quoted", `root: "a\tb"`, "a\tb", true},
+		{"single quoted", "root: 'C:\\dir'", `C:\dir`, true},
+		{"single quote escape", "root: 'it''s'", "it's", true},
+		{"quoted key", `"root": sub`, "sub", true},
+		{"comments", "# header\nroot: sub # trailing: comment\n", "sub", true},
+		{"document start", "---\nroot: sub\n", "sub", true},
+		{"document start with comment", "--- # config\nroot: sub\n...\n", "sub", true},
+		{"nested mapping skipped", "server:\n  root: wrong\nroot: sub\n", "sub", true},
+		{"nested only", "server:\n  root: wrong\n", "", false},
+		{"list items skipped", "- root: wrong\nroot: sub\n", "sub", true},
+		{"multi-line value before", "desc: \"a\n  root: wrong\"\nroot: sub\n", "sub", true},
+		{"block scalar before", "desc: |\n  root: wrong\n\n  more\nroot: sub\n", "sub", true},
+		{"literal block scalar", "root: |\n  /srv/app\n", "/srv/app", true},
+		{"literal block keeps lines", "root: |-\n  a\n  b\nnext: x\n", "a\nb", true},
+		{"folded block scalar", "root: >\n  /srv\n  /app\n\nnext: x\n", "/srv /app", true},
+		{"folded plain scalar", "root: /srv\n  /app\n", "/srv /app", true},
+		{"empty value", "root:\n", "", true},
+		{"missing", "name: x\n", "", false},
+	})
+
+	if got, _, err := yamlConfigKey([]byte("root: 'unterminated\n"), "root"); err == nil {
+		t.Errorf("yamlConfigKey() = %q, want error", got)
+	}
+}
+
+func TestSetRootFromConfigFile(t *testing.T) {
+	resetGroot(t)
+	dir := tempDir(t)
+	projectDir := filepath.Join(dir, "cmd", "app")
+	writeFile(t, filepath.Join(dir, "app.toml"), "deps = [\n  \"a\",\n]\nroot = \"srv\"\n")
+	if err := os.MkdirAll(filepath.Join(dir, "srv"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	useProjectDir(t, projectDir)
+
+	if err := SetRootFromConfigFile("app.toml", "root"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := GetRoot(), filepath.Join(dir, "srv"); got != want {
+		t.Errorf("GetRoot() = %q, want %q", got, want)
+	}
+	if err := SetRootFromConfigFile("app.toml", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetRoot(); got != dir {
+		t.Errorf("GetRoot() without root key = %q, want %q", got, dir)
+	}
+}
+
+func TestFindGitRootFromRelativePath(t *testing.T) {
+	resetGroot(t)
+	repo := tempDir(t)
+	other := tempDir(t)
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if root := FindGitRootFrom(other); root != "" {
+		t.Skipf("temp dir is inside git repository %s", root)
+	}
+
+	chdir(t, repo)
+	for _, path := range []string{".", "pkg", filepath.Join("pkg", "..", "pkg")} {
+		if root := FindGitRootFrom(path); root != repo {
+			t.Errorf("FindGitRootFrom(%q) in repo = %q, want %q", path, root, repo)
+		}
+	}
+
+	// The same relative path must not reuse the answer cached for the repo
+	if err := os.Chdir(other); err != nil {
+		t.Fatal(err)
+	}
+	if root := FindGitRootFrom("."); root != "" {
+		t.Errorf("FindGitRootFrom(.) outside repo = %q, want empty", root)
+	}
+}
+
+func TestSetIdempotent(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	writeFile(t, filepath.Join(root, "app.id"), "")
+	writeFile(t, filepath.Join(root, ".env"), "GROOT_TEST_A=1\n")
+	useProjectDir(t, root)
+	unsetEnv(t, "GROOT_TEST_A")
+	SetIdempotent(true)
+	loads := 0
+	OnEnvSet(func(key, value, sourceFile string) { loads++ })
+
+	first, err := SetRootWithResult("app.id", ".env")
+	if err != nil || first.NoOp {
+		t.Fatalf("first SetRootWithResult() = %+v, %v, want a load", first, err)
+	}
+	repeat, err := SetRootWithResult("app.id", ".env")
+	if err != nil || !repeat.NoOp || !reflect.DeepEqual(repeat.EnvFiles, first.EnvFiles) {
+		t.Fatalf("repeated SetRootWithResult() = %+v, %v, want NoOp with the first result", repeat, err)
+	}
+	if loads != 1 {
+		t.Fatalf("OnEnvSet called %d times, want 1", loads)
+	}
+
+	// Changed options make the same call reload the env files
+	opts := DefaultEnvOptions()
+	opts.PreserveExistingEnv = false
+	SetEnvOptions(opts)
+	reload, err := SetRootWithResult("app.id", ".env")
+	if err != nil || reload.NoOp {
+		t.Fatalf("SetRootWithResult() after SetEnvOptions = %+v, %v, want a reload", reload, err)
+	}
+	if loads != 2 {
+		t.Errorf("OnEnvSet called %d times, want 2", loads)
+	}
+
+	// So does a root change
+	if err := SetRootFromPath(root); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := SetRootWithResult("app.id", ".env"); err != nil || result.NoOp {
+		t.Errorf("SetRootWithResult() after a root change = %+v, %v, want a reload", result, err)
+	}
+}
+
+func TestSetRootFromOnEnvSet(t *testing.T) {
+	resetGroot(t)
+	root := tempDir(t)
+	writeFile(t, filepath.Join(root, "app.id"), "")
+	writeFile(t, filepath.Join(root, ".env"), "GROOT_TEST_A=1\n")
+	useProjectDir(t, root)
+	unsetEnv(t, "GROOT_TEST_A")
+	SetIdempotent(true)
+	depth := 0
+	OnEnvSet(func(key, value, sourceFile string) {
+		if depth++; depth == 1 {
+			SetIdempotent(true)
+			SetRoot("app.id", ".env")
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- SetRoot("app.id", ".env") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetRoot called from OnEnvSet deadlocked")
+	}
+}
+
+func TestNoEntryFile(t *testing.T) {
+	resetGroot(t)
+	if _, err := NewStrict(Options{EntryFile: " "}); !errors.Is(err, ErrNoEntryFile) {
+		t.Errorf("NewStrict() = %v, want ErrNoEntryFile", err)
+	}
+	if err := SetRoot("", ".env"); !errors.Is(err, ErrNoEntryFile) {
+		t.Errorf("SetRoot() = %v, want ErrNoEntryFile", err)
+	}
+	if _, err := CountEntryMatches(""); !errors.Is(err, ErrNoEntryFile) {
+		t.Errorf("CountEntryMatches() = %v, want ErrNoEntryFile", err)
+	}
+	if got := GetRoot(); got != "" {
+		t.Errorf("GetRoot() = %q, want empty", got)
+	}
+}
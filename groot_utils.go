@@ -1,7 +1,9 @@
 package groot
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,33 +36,209 @@ func ensureCleanPath(path string) string {
 	)
 }
 
-// cleanFilenames removes duplicate filenames and returns a slice of unique filenames
+// CleanPathFor trims spaces, converts both / and \ to sep and collapses
+// repeated separators, regardless of the host OS. Use it to build paths for
+// another target, e.g. POSIX paths for a container image from Windows.
+func CleanPathFor(path string, sep rune) string {
+	path = strings.TrimSpace(path)
+	var b strings.Builder
+	b.Grow(len(path))
+	lastSep := false
+	for _, r := range path {
+		if r == '/' || r == '\\' || r == sep {
+			if !lastSep {
+				b.WriteRune(sep)
+			}
+			lastSep = true
+			continue
+		}
+		b.WriteRune(r)
+		lastSep = false
+	}
+	return b.String()
+}
+
+// absCleanPath returns the absolute, cleaned form of path
+func absCleanPath(path string) (string, error) {
+	abs, err := filepath.Abs(ensureCleanPath(path))
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	return abs, nil
+}
+
+// cleanFilenames removes duplicate filenames and returns the unique filenames
+// in their original order
 func cleanFilenames(filenames ...string) []string {
-	uniqueFilenames := make(map[string]struct{})
+	seen := make(map[string]struct{}, len(filenames))
+	uniqueFilenames := make([]string, 0, len(filenames))
 	for _, filename := range filenames {
 		filename = replaceStringByte(strings.TrimSpace(filename), os.PathSeparator, '/')
 		if filename == "" || strings.Contains(filename, "/") {
 			// skip empty filenames and filenames with slashes (paths)
 			continue
 		}
-		uniqueFilenames[filename] = struct{}{}
+		if _, dup := seen[filename]; dup {
+			continue
+		}
+		seen[filename] = struct{}{}
+		uniqueFilenames = append(uniqueFilenames, filename)
+	}
+	return uniqueFilenames
+}
+
+// dedupeResolvedPaths removes paths pointing to the same file once symbolic
+// links are resolved, keeping the first occurrence and the original spelling
+func dedupeResolvedPaths(paths []string) []string {
+	seen := make(map[string]struct{}, len(paths))
+	unique := make([]string, 0, len(paths))
+	for _, path := range paths {
+		key := filepath.Clean(path)
+		if abs, err := filepath.Abs(key); err == nil {
+			key = abs
+		}
+		if resolved, err := filepath.EvalSymlinks(key); err == nil {
+			key = resolved
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, path)
+	}
+	return unique
+}
+
+// escapesBase reports whether a cleaned path is absolute or leaves its base via ".."
+func escapesBase(path string) bool {
+	return filepath.IsAbs(path) || path == ".." ||
+		strings.HasPrefix(path, ".."+string(os.PathSeparator))
+}
+
+// escapesRoot reports whether a cleaned name joined to root would leave it.
+// Unlike escapesBase it also rejects volume-relative paths such as C:dir,
+// which are not absolute but do not stay under root either.
+func escapesRoot(name string) bool {
+	return escapesBase(name) || filepath.VolumeName(name) != ""
+}
+
+// isWithin reports whether path is base or one of its descendants
+func isWithin(base, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(path))
+	if err != nil {
+		return false
 	}
-	uniqueFilenamesSlice := make([]string, 0, len(uniqueFilenames))
-	for filename := range uniqueFilenames {
-		uniqueFilenamesSlice = append(uniqueFilenamesSlice, filename)
+	return !escapesBase(rel)
+}
+
+// cleanRelPaths normalizes relative paths and removes duplicates, keeping order.
+// Absolute paths and paths escaping their base directory are skipped.
+func cleanRelPaths(paths ...string) []string {
+	seen := make(map[string]struct{})
+	cleanPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		path = filepath.Clean(ensureCleanPath(path))
+		if path == "." || escapesBase(path) {
+			continue
+		}
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		cleanPaths = append(cleanPaths, path)
 	}
-	return uniqueFilenamesSlice
+	return cleanPaths
 }
 
-// findFiles returns a slice of found files in a directory
+// findFiles returns a slice of found files in a directory.
+// Names containing glob metacharacters (*?[) are expanded with filepath.Glob;
+// a file literally named so (e.g. "a[b].env") is also found, first. Other
+// names are matched with os.Stat.
 func findFiles(dirPath string, fileNames []string) ([]string, error) {
 	var foundFiles []string
 	for _, fileName := range fileNames {
-		files, err := filepath.Glob(filepath.Join(dirPath, fileName))
+		path := filepath.Join(dirPath, fileName)
+		_, err := os.Stat(path)
+		literal := err == nil
+		if literal {
+			foundFiles = append(foundFiles, path)
+		}
+		if !strings.ContainsAny(fileName, "*?[") {
+			continue
+		}
+		files, err := filepath.Glob(path)
 		if err != nil {
 			return nil, ers.Wrap(err)
 		}
-		foundFiles = append(foundFiles, files...)
+		for _, file := range files {
+			if !literal || file != path {
+				foundFiles = append(foundFiles, file)
+			}
+		}
 	}
 	return foundFiles, nil
 }
+
+// checkSearchable returns an error if looking up any of names in dir, or
+// listing dir for names with glob metacharacters, is denied permission
+func checkSearchable(dir string, names []string) error {
+	for _, name := range names {
+		if strings.ContainsAny(name, "*?[") {
+			f, err := os.Open(dir)
+			if errors.Is(err, fs.ErrPermission) {
+				return ers.Wrapf(err, "cannot search %s", dir)
+			}
+			if err == nil {
+				f.Close()
+			}
+		}
+		if _, err := os.Lstat(filepath.Join(dir, name)); errors.Is(err, fs.ErrPermission) {
+			return ers.Wrapf(err, "cannot search %s", dir)
+		}
+	}
+	return nil
+}
+
+// findRootFrom returns the first directory from startPath upward for which
+// pred returns true, or an empty string if none matches
+func findRootFrom(startPath string, pred func(dir string) (bool, error)) (string, error) {
+	for _, path := range IterateThroughPath(startPath) {
+		ok, err := pred(path)
+		if err != nil {
+			return "", ers.Wrapf(err, "predicate failed for %s", path)
+		}
+		if ok {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// hasAnyFile reports whether dir directly contains any of the named entries
+func hasAnyFile(dir string, names []string) bool {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// evalSymlinksPartial resolves symbolic links in an absolute path, resolving
+// its nearest existing ancestor when the path itself does not exist
+func evalSymlinksPartial(path string) string {
+	rest := ""
+	for current := path; ; current = filepath.Dir(current) {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if current == filepath.Dir(current) {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(current), rest)
+	}
+}
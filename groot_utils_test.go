@@ -0,0 +1,64 @@
+package groot
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestFindFiles(t *testing.T) {
+	dir := tempDir(t)
+	names := []string{"a[b].env", "ab.env", "x.env", "y.env", "notes.txt"}
+	if runtime.GOOS != "windows" {
+		// * and ? are not allowed in Windows filenames
+		names = append(names, "*.env")
+	}
+	for _, name := range names {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"ab.env", []string{"ab.env"}},
+		{"missing.env", nil},
+		// A literal match comes first, then the glob expansion
+		{"a[b].env", []string{"a[b].env", "ab.env"}},
+		{"[xy].env", []string{"x.env", "y.env"}},
+		{"?b.env", []string{"ab.env"}},
+		{"[^abx*].env", []string{"y.env"}},
+		{"*.txt", []string{"notes.txt"}},
+		{"*.pem", nil},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name string
+			want []string
+		}{"*.env", []string{"*.env", "a[b].env", "ab.env", "x.env", "y.env"}})
+	}
+	for _, tt := range tests {
+		got, err := findFiles(dir, []string{tt.name})
+		if err != nil {
+			t.Fatalf("findFiles(%q) error = %v", tt.name, err)
+		}
+		var want []string
+		for _, name := range tt.want {
+			want = append(want, filepath.Join(dir, name))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("findFiles(%q) = %q, want %q", tt.name, got, want)
+		}
+	}
+
+	// Several names are matched in order
+	got, err := findFiles(dir, []string{"y.env", "[ax]*.env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "y.env"), filepath.Join(dir, "a[b].env"), filepath.Join(dir, "ab.env"), filepath.Join(dir, "x.env")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findFiles() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package groot
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ovila98/ers"
+)
+
+// Maximum path length on Windows without the long path prefix, excluding the
+// terminating NUL of MAX_PATH (260)
+const windowsMaxPath = 259
+
+// Device names Windows reserves, with or without an extension
+var windowsReservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {}, "CONIN$": {}, "CONOUT$": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {},
+	"COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {},
+	"LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// ValidateWindowsPath checks that path can be created on Windows, whatever the
+// current platform, so tools can validate generated paths ahead of time.
+//
+// Rules enforced:
+//
+// - the path is at most 259 UTF-16 code units (MAX_PATH) unless it starts
+// with \\?\
+//
+// - no component is a reserved device name (CON, PRN, AUX, NUL, CONIN$,
+// CONOUT$, COM1-9, LPT1-9), case-insensitively and with or without an extension
+//
+// - no component ends with a dot or a space ("." and ".." are allowed)
+//
+// - no component contains < > : " | ? * or control characters (except the
+// colon of a leading drive letter)
+//
+// Both / and \ are treated as separators.
+func ValidateWindowsPath(path string) error {
+	if path == "" {
+		return ers.New("path cannot be empty")
+	}
+
+	longPath := strings.HasPrefix(path, `\\?\`)
+	if longPath {
+		path = path[4:]
+	} else if n := len(utf16.Encode([]rune(path))); n > windowsMaxPath {
+		return ers.New("path is %d characters long, more than %d", n, windowsMaxPath)
+	}
+
+	// Allow a leading drive letter such as C:
+	if len(path) >= 2 && path[1] == ':' && isASCIILetter(path[0]) {
+		path = path[2:]
+	}
+
+	for _, component := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if component == "." || component == ".." {
+			continue
+		}
+		if strings.HasSuffix(component, ".") || strings.HasSuffix(component, " ") {
+			return ers.New("path component %q ends with a dot or space", component)
+		}
+		for _, r := range component {
+			if r < 32 || strings.ContainsRune(`<>:"|?*`, r) {
+				return ers.New("path component %q contains invalid character %q", component, r)
+			}
+		}
+		name := strings.ToUpper(component)
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			name = name[:i]
+		}
+		if _, reserved := windowsReservedNames[strings.TrimRight(name, " ")]; reserved {
+			return ers.New("path component %q is a reserved name", component)
+		}
+	}
+	return nil
+}
+
+// isASCIILetter reports whether b is an ASCII letter
+func isASCIILetter(b byte) bool {
+	return ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
@@ -0,0 +1,62 @@
+package groot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateWindowsPath(t *testing.T) {
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{`C:\app\config\.env`, true},
+		{"app/./config/../.env", true},
+		{"", false},
+		// Reserved device names, whatever the case or extension
+		{`C:\app\con`, false},
+		{"app/Nul.txt", false},
+		{"app/COM1.log", false},
+		{"app/lpt9", false},
+		{"app/CONIN$", false},
+		{"app/conout$.txt", false},
+		{"app/CONSOLE", true},
+		{"app/COM10", true},
+		// Trailing dots and spaces
+		{"app/name.", false},
+		{"app/name ", false},
+		// Invalid characters
+		{"app/a<b", false},
+		{"app/a:b", false},
+		{`app/a"b`, false},
+		{"app/a|b", false},
+		{"app/a?b", false},
+		{"app/a*b", false},
+		{"app/a\tb", false},
+		{"C:", true},
+		{"1:/app", false},
+		// Length is counted in UTF-16 code units, not bytes
+		{"C:/" + strings.Repeat("a", 256), true},
+		{"C:/" + strings.Repeat("a", 257), false},
+		{"C:/" + strings.Repeat("é", 256), true},
+		{"C:/" + strings.Repeat("😀", 128), true},
+		{"C:/" + strings.Repeat("😀", 129), false},
+		{`\\?\C:\` + strings.Repeat("a", 300), true},
+		{`\\?\C:\` + strings.Repeat("a", 300) + `\nul`, false},
+	}
+	for _, tt := range tests {
+		name := tt.path
+		if len(name) > 40 {
+			name = name[:40] + "..."
+		}
+		t.Run(name, func(t *testing.T) {
+			err := ValidateWindowsPath(tt.path)
+			if tt.ok && err != nil {
+				t.Errorf("ValidateWindowsPath() = %v, want nil", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("ValidateWindowsPath() = nil, want error")
+			}
+		})
+	}
+}
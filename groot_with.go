@@ -0,0 +1,60 @@
+package groot
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ovila98/ers"
+)
+
+// The *With functions are stateless counterparts of the root helpers: they
+// take the root explicitly instead of reading the global one, so callers can
+// carry it through their own plumbing (see also ContextWithRoot).
+
+// FromRootWith joins the given path elements with root as FromRoot does with
+// the global root. If root is empty or the first path is absolute, joins
+// paths without root.
+func FromRootWith(root string, path ...string) string {
+	if strings.TrimSpace(root) == "" || (len(path) > 0 && filepath.IsAbs(path[0])) {
+		return filepath.Join(path...)
+	}
+	return filepath.Join(root, filepath.Join(path...))
+}
+
+// GetRelativeToRootWith returns the relative path from root to path as
+// GetRelativeToRoot does with the global root.
+// Returns ErrRootNotSet if root is empty and ErrDifferentVolume if path is
+// on another volume.
+func GetRelativeToRootWith(root, path string) (string, error) {
+	if strings.TrimSpace(root) == "" {
+		return "", ers.Wrap(ErrRootNotSet)
+	}
+	cleanRoot, err := CanonPath(root)
+	if err != nil {
+		return "", ers.Wrap(err)
+	}
+	rel, err := relFromCanonRoot(cleanRoot, path)
+	return rel, ers.Wrap(err)
+}
+
+// IsInRootWith reports whether path is root or one of its descendants, as
+// IsInRoot does with the global root. Returns false if root is empty.
+func IsInRootWith(root, path string) bool {
+	rel, err := GetRelativeToRootWith(root, path)
+	return err == nil && !escapesBase(rel)
+}
+
+// ResolveInRootWith joins the given path elements with root and returns the
+// result, which must stay within root: relative paths cannot climb above it
+// with "..", and absolute paths must point inside it.
+// Returns ErrRootNotSet if root is empty and an error if the path escapes root.
+func ResolveInRootWith(root string, path ...string) (string, error) {
+	if strings.TrimSpace(root) == "" {
+		return "", ers.Wrap(ErrRootNotSet)
+	}
+	joined := FromRootWith(root, path...)
+	if !IsInRootWith(root, joined) {
+		return "", ers.New("path %s escapes root %s", joined, root)
+	}
+	return joined, nil
+}